@@ -7,13 +7,22 @@ package jsonobj
 
 import (
 	"bytes"
+	"encoding"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"io"
+	"math"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
-// ErrJSON is this package's base error.
+// ErrJSON is this package's base error. Errors returned by this package are
+// either one of the ErrJSON values declared below or errors wrapping them,
+// such as *PathError, so they should be tested for with errors.Is.
 type ErrJSON struct {
 	ErrorString string
 }
@@ -24,6 +33,15 @@ func (err ErrJSON) Error() string {
 }
 
 var (
+	// ErrConflict is returned by Unflatten when paths of two entries address
+	// the same element or one addresses a descendant of a non-container value
+	// of the other.
+	ErrConflict = &ErrJSON{"conflicting paths"}
+
+	// ErrDuplicateKey is returned by UnmarshalStrict when an Object contains
+	// the same key more than once.
+	ErrDuplicateKey = &ErrJSON{"duplicate key"}
+
 	// ErrInvalidIn is returned by the Set method when the in parameter is
 	// invalid, most likely nil.
 	ErrInvalidIn = &ErrJSON{"invalid in value"}
@@ -33,10 +51,22 @@ var (
 	// the kind of value returned by Get.
 	ErrInvalidOut = &ErrJSON{"invalid out type"}
 
+	// ErrInvalidPatch is returned by ApplyPatch when the patch document or
+	// one of its operations is malformed.
+	ErrInvalidPatch = &ErrJSON{"invalid patch"}
+
 	// ErrInvalidPath is returned by Get/Set methods when the specified path
 	// of the JSON element is malformed.
 	ErrInvalidPath = &ErrJSON{"invalid path"}
 
+	// ErrInvalidTag is returned when a struct field tag of this package is
+	// malformed.
+	ErrInvalidTag = &ErrJSON{"invalid struct tag"}
+
+	// ErrNoTarget is returned by DecodeUnion when no target is registered
+	// for the discriminator value.
+	ErrNoTarget = &ErrJSON{"no target for discriminator value"}
+
 	// ErrNotFound is returned when a path form is ok but the element under
 	// that path does not exist.
 	ErrNotFound = &ErrJSON{"element not found"}
@@ -44,6 +74,14 @@ var (
 	// ErrOutOfRange is returned when addressing out of range Array element.
 	ErrOutOfRange = &ErrJSON{"index out of range"}
 
+	// ErrTestFailed is returned by ApplyPatch when a "test" operation
+	// finds a value different from the expected one.
+	ErrTestFailed = &ErrJSON{"patch test failed"}
+
+	// ErrTooDeep is returned by UnmarshalLimited when Objects and Arrays of
+	// the document are nested deeper than allowed.
+	ErrTooDeep = &ErrJSON{"maximum nesting depth exceeded"}
+
 	// ErrTruncate is returned when a value was successfully assigned to out
 	// but the output variable was truncated or overflowed as a result of the
 	// typecast.
@@ -51,8 +89,65 @@ var (
 
 	// ErrTypeMissmatch is returned on unmatched in and out parameter types.
 	ErrTypeMissmatch = &ErrJSON{"in out type missmatch"}
+
+	// ErrUnknownField is returned by Get when an Object assigned to a closed
+	// struct contains a key that matches none of its fields.
+	ErrUnknownField = &ErrJSON{"unknown field"}
 )
 
+// UnknownFieldError is returned when an Object key matches no field of a
+// struct it is assigned to and unknown keys are not allowed. It wraps
+// ErrUnknownField so it can be tested for with errors.Is.
+type UnknownFieldError struct {
+	Key string // Key is the unmatched Object key.
+}
+
+// DuplicateKeyError is returned by UnmarshalStrict when an Object contains
+// the same key more than once. It wraps ErrDuplicateKey so it can be tested
+// for with errors.Is.
+type DuplicateKeyError struct {
+	Path string // Path is the path of the Object containing the key.
+	Key  string // Key is the duplicated key.
+}
+
+// Error implements the Error interface.
+func (err *DuplicateKeyError) Error() string {
+	return ErrDuplicateKey.Error() + ": " + strconv.Quote(err.Key) + " in " + strconv.Quote(err.Path)
+}
+
+// Unwrap returns ErrDuplicateKey.
+func (err *DuplicateKeyError) Unwrap() error {
+	return ErrDuplicateKey
+}
+
+// KindError is returned by Require when an element is not of the required
+// kind. It wraps ErrTypeMissmatch so it can be tested for with errors.Is.
+type KindError struct {
+	Path     string // Path is the path of the element.
+	Expected string // Expected is the required kind.
+	Actual   string // Actual is the kind of the element.
+}
+
+// Error implements the Error interface.
+func (err *KindError) Error() string {
+	return "expected " + err.Expected + " but found " + err.Actual + " at " + strconv.Quote(err.Path)
+}
+
+// Unwrap returns ErrTypeMissmatch.
+func (err *KindError) Unwrap() error {
+	return ErrTypeMissmatch
+}
+
+// Error implements the Error interface.
+func (err *UnknownFieldError) Error() string {
+	return ErrUnknownField.Error() + ": " + strconv.Quote(err.Key)
+}
+
+// Unwrap returns ErrUnknownField.
+func (err *UnknownFieldError) Unwrap() error {
+	return ErrUnknownField
+}
+
 // JSON is an intermediate type for reading/writing values to/from a JSON
 // without having to first define a type for its' structure. It's convenient
 // at the price of speed. Extensive type checking in the internal callchain
@@ -72,9 +167,24 @@ var (
 // 	jf.Get("planets[0].name", &myVar).
 // To get 42nd Object from some JSON containing an array of objects:
 // 	jf.Get("[42]", &myVar).
-// Same rules apply to Set method.
+// Same rules apply to Set method. An empty path addresses the root element.
+// Object keys containing dots, brackets or quotes can be given as double
+// quoted strings in square brackets:
+// 	jf.Get(`["weird.key"].name`, &myVar).
 type JSON struct {
 	intf interface{} // iface is the unmarshaled JSON object.
+	tag  string      // tag is the struct tag name used by Get, "json" if empty.
+
+	lenient bool      // lenient enables parsing Strings into numeric outputs.
+	unknown *[]string // unknown collects Object keys not matched by structs.
+	closed  bool      // closed disallows Object keys not matched by structs.
+	trunc   bool      // trunc discards precision of Numbers instead of failing.
+
+	// matcher matches Object keys to names of untagged struct fields.
+	matcher func(key, field string) bool
+
+	// positions maps canonical paths to source offsets of their elements.
+	positions map[string][2]int
 }
 
 // Unmarshal constructs a new JSON object from a slice of bytes.
@@ -87,372 +197,3284 @@ func Unmarshal(b []byte) (*JSON, error) {
 	return p, nil
 }
 
-// find looks for a child element in the JSON using the specified path and
-// returns the key Value with the applicable type that adresses it in its
-// container (be it map or slice), the value itself it as an interface and
-// a nil error on success. It returns just the error if one occured.
-func (j *JSON) find(path string, parent bool) (reflect.Value, interface{}, error) {
+// UnmarshalUseNumber is like Unmarshal but keeps Numbers as json.Number
+// values instead of converting them to float64, so that integers which
+// cannot be represented exactly by a float64, i.e. larger than 2^53, keep
+// their precision when read by Get into integer outputs, including through
+// pointers, json.Number outputs or types implementing json.Unmarshaler such
+// as big.Int. Such Numbers are assigned to interface outputs as json.Number
+// and converted to float64 only for float outputs. Elsewhere, i.e. when
+// aggregating values, such Numbers behave like float64 values, while
+// comparisons and Canonical keep integers an int64 holds exact.
+func UnmarshalUseNumber(b []byte) (*JSON, error) {
 
-	parentKey := reflect.ValueOf(nil)
+	// Validate b first so that malformed input, including trailing data,
+	// returns the same errors as Unmarshal.
+	if err := json.Unmarshal(b, &json.RawMessage{}); err != nil {
+		return nil, err
+	}
+	p := &JSON{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&p.intf); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
 
-	keys := strings.Split(path, ".")
-	if len(keys) == 0 {
-		return parentKey, nil, ErrInvalidPath
+// UnmarshalStrict is like Unmarshal but returns a *DuplicateKeyError naming
+// the first duplicated key if any Object in b contains the same key more
+// than once, which the json package silently accepts keeping the last value.
+func UnmarshalStrict(b []byte) (*JSON, error) {
+
+	j, err := Unmarshal(b)
+	if err != nil {
+		return nil, err
 	}
-	for _, v := range keys {
-		if v == "" {
-			return parentKey, nil, ErrInvalidPath
-		}
+	if err := checkDuplicates(b); err != nil {
+		return nil, err
 	}
+	return j, nil
+}
 
-	result := j.intf // Updated through the loop and returned after it.
-	var err error
-	kl := len(keys)
-	a, b, i := -1, -1, -1
-	for keyi, keyv := range keys {
+// checkDuplicates returns a *DuplicateKeyError if an Object in the valid JSON
+// document b contains a duplicate key.
+func checkDuplicates(b []byte) error {
 
-		a = strings.LastIndex(keyv, "[")
-		b = strings.LastIndex(keyv, "]")
+	// seen holds keys of Objects being decoded keyed by their paths.
+	seen := map[string]map[string]bool{}
 
-		if a >= 0 {
-			if b <= a {
-				return parentKey, nil, ErrInvalidPath
-			}
-			i, err = strconv.Atoi(keyv[a+1 : b])
-			if err != nil {
-				return parentKey, nil, ErrInvalidPath
-			}
-			keyv = keyv[:a]
+	return scanTokens(b, func(path Path, key string) error {
+		p := path.String()
+		if seen[p] == nil {
+			seen[p] = map[string]bool{}
 		}
+		if seen[p][key] {
+			return &DuplicateKeyError{Path: p, Key: key}
+		}
+		seen[p][key] = true
+		return nil
+	}, func(path Path, start, end int) error {
+		delete(seen, path.String())
+		return nil
+	})
+}
 
-		if keyv == "" {
-			si, ok := result.([]interface{})
-			if !ok {
-				return parentKey, nil, ErrNotFound
-			}
-			if i < 0 || i >= len(si) {
-				return parentKey, nil, ErrOutOfRange
-			}
-			if parent && keyi == kl-1 {
-				parentKey = reflect.ValueOf(i)
-				result = si
-			} else {
-				result = si[i]
-			}
-			i = -1
-		} else {
-			mi, ok := result.(map[string]interface{})
-			if !ok {
-				return parentKey, nil, ErrNotFound
+// scanTokens walks the valid JSON document b token by token. It calls onKey
+// for each Object key with the path of the Object and onValue for each
+// element once it has been decoded with its path and the offsets of its
+// first byte and of the byte following its last byte in b. If a callback
+// returns an error scanTokens stops and returns it. Path passed to callbacks
+// is reused between calls and must not be retained.
+func scanTokens(b []byte, onKey func(path Path, key string) error, onValue func(path Path, start, end int) error) error {
+
+	// frame is an Object or Array being decoded.
+	type frame struct {
+		start int  // start is the offset of the opening delimiter.
+		obj   bool // obj is true for Objects.
+		key   bool // key is true if an Object key is expected.
+		index int  // index is the index of the next Array element.
+	}
+	stack := []*frame{}
+	path := Path{}
+
+	// done reports the element under path as decoded and updates the state
+	// of its parent container.
+	done := func(start, end int) error {
+		if err := onValue(path, start, end); err != nil {
+			return err
+		}
+		if len(stack) == 0 {
+			return nil
+		}
+		path = path[:len(path)-1]
+		if top := stack[len(stack)-1]; top.obj {
+			top.key = true
+		}
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for {
+		start := int(dec.InputOffset())
+		for start < len(b) && strings.IndexByte(" \t\r\n,:", b[start]) >= 0 {
+			start++
+		}
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		end := int(dec.InputOffset())
+		if n := len(stack); n > 0 && stack[n-1].key {
+			if tok == json.Delim('}') {
+				top := stack[n-1]
+				stack = stack[:n-1]
+				if err := done(top.start, end); err != nil {
+					return err
+				}
+				continue
 			}
-			if i < 0 && parent && keyi == kl-1 {
-				parentKey = reflect.ValueOf(keyv)
-				result = mi
-				break
+			key := tok.(string)
+			if err := onKey(path, key); err != nil {
+				return err
 			}
-			iv, ok := mi[keyv]
-			if !ok {
-				return parentKey, nil, ErrNotFound
+			stack[n-1].key = false
+			path = append(path, Segment{Key: key})
+			continue
+		}
+		if tok == json.Delim(']') {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if err := done(top.start, end); err != nil {
+				return err
 			}
-			result = iv
-			if i > -1 {
-				sv, ok := iv.([]interface{})
-				if !ok {
-					return parentKey, nil, ErrNotFound
-				}
-				if i >= len(sv) {
-					return parentKey, nil, ErrOutOfRange
-				}
-				if parent && keyi == kl-1 {
-					parentKey = reflect.ValueOf(i)
-					result = sv
-					break
-				}
-				result = sv[i]
+			continue
+		}
+		if n := len(stack); n > 0 && !stack[n-1].obj {
+			path = append(path, Segment{Index: stack[n-1].index, Array: true})
+			stack[n-1].index++
+		}
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, &frame{start: start, obj: true, key: true})
+		case json.Delim('['):
+			stack = append(stack, &frame{start: start})
+		default:
+			if err := done(start, end); err != nil {
+				return err
 			}
 		}
-		i = -1
 	}
+}
 
-	return parentKey, result, nil
+// UnmarshalWithPositions is like Unmarshal but also records the byte offsets
+// of every element in b which can then be retrieved with Position. Recorded
+// offsets describe b and are not updated as the JSON is modified.
+func UnmarshalWithPositions(b []byte) (*JSON, error) {
+
+	j, err := Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+	if j.positions, err = positions(b); err != nil {
+		return nil, err
+	}
+	return j, nil
 }
 
-// assign recursively assigns in to out in a manner defined by this JSON type.
-func (j *JSON) assign(in, out reflect.Value) error {
+// Position returns the byte offsets in the source document of the element
+// under path, start being the offset of its first byte and end the offset
+// following its last byte. The JSON must have been constructed with
+// UnmarshalWithPositions. If path is malformed returns ErrInvalidPath; if the
+// source contained no element at path returns ErrNotFound.
+func (j *JSON) Position(path string) (start, end int, err error) {
 
-	if !in.IsValid() {
+	p, err := parse(path)
+	if err != nil {
+		return -1, -1, err
+	}
+	pos, ok := j.positions[p.String()]
+	if !ok {
+		return -1, -1, ErrNotFound
+	}
+	return pos[0], pos[1], nil
+}
+
+// positions returns the byte offsets of all elements of the valid JSON
+// document b keyed by their canonical paths.
+func positions(b []byte) (map[string][2]int, error) {
+
+	result := map[string][2]int{}
+	err := scanTokens(b, func(path Path, key string) error {
+		return nil
+	}, func(path Path, start, end int) error {
+		result[path.String()] = [2]int{start, end}
 		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	switch out.Kind() {
+// UnmarshalLimited is like Unmarshal but returns ErrTooDeep if Objects and
+// Arrays in b are nested deeper than maxDepth as defined by MaxDepth. Nesting
+// is checked before decoding so that documents exceeding the limit are
+// rejected without being decoded.
+func UnmarshalLimited(b []byte, maxDepth int) (*JSON, error) {
 
-	case reflect.Slice:
-		sl := reflect.MakeSlice(out.Type(), in.Len(), in.Len())
-		for i := 0; i < in.Len(); i++ {
-			if err := j.assign(in.Index(i), sl.Index(i)); err != nil {
-				return err
+	depth, str, esc := 0, false, false
+	for _, c := range b {
+		switch {
+		case esc:
+			esc = false
+		case str:
+			if c == '\\' {
+				esc = true
+			} else if c == '"' {
+				str = false
+			}
+		case c == '"':
+			str = true
+		case c == '[' || c == '{':
+			if depth++; depth > maxDepth {
+				return nil, ErrTooDeep
 			}
+		case c == ']' || c == '}':
+			depth--
 		}
-		out.Set(sl)
+	}
+	return Unmarshal(b)
+}
 
-	case reflect.Struct:
-		keys := in.MapKeys()
-		match := false
-		for i := 0; i < out.NumField(); i++ {
+// Layer deep merges docs in order into a new JSON, later documents
+// overriding values of earlier ones. Objects are merged recursively while
+// all other values, including Arrays, replace the value being overridden.
+// A null value in a later document deletes the key it overrides. Use
+// LayerKeepNull to store nulls instead. Docs are left unmodified. If no
+// docs are given or any of them is nil returns ErrInvalidIn.
+func Layer(docs ...*JSON) (*JSON, error) {
+	return layer(false, docs)
+}
 
-			if !out.Field(i).CanSet() {
-				continue
-			}
-			fld := out.Type().Field(i)
-			tags := strings.Split(fld.Tag.Get("json"), ",")
+// LayerKeepNull is like Layer but stores null values from later documents
+// instead of deleting keys they override.
+func LayerKeepNull(docs ...*JSON) (*JSON, error) {
+	return layer(true, docs)
+}
 
-			for k := 0; k < len(keys); k++ {
-				if len(tags) > 0 {
-					match = keys[k].String() == tags[0]
-				} else {
-					match = strings.EqualFold(keys[k].String(), fld.Name)
-				}
-				if !match {
-					continue
-				}
-				val := in.MapIndex(keys[k])
-				if err := j.assign(val, out.Field(i)); err != nil {
-					return err
-				}
-				break
-			}
+// layer implements Layer and LayerKeepNull.
+func layer(keepNull bool, docs []*JSON) (*JSON, error) {
+
+	if len(docs) == 0 {
+		return nil, ErrInvalidIn
+	}
+	for _, doc := range docs {
+		if doc == nil {
+			return nil, ErrInvalidIn
 		}
+	}
 
-	// Booleans, Strings and Numbers are directly
-	// assigned as json package defines.
+	result := clone(docs[0].intf)
+	for _, doc := range docs[1:] {
+		result = merge(result, doc.intf, keepNull)
+	}
+	return &JSON{intf: result}, nil
+}
 
-	case reflect.Bool:
-		v, ok := in.Interface().(bool)
-		if !ok {
-			return ErrInvalidOut
-		}
-		out.Set(reflect.ValueOf(v))
-	case reflect.String:
-		v, ok := in.Interface().(string)
-		if !ok {
-			return ErrInvalidOut
-		}
-		out.Set(reflect.ValueOf(v))
-	case reflect.Float64:
-		v, ok := in.Interface().(float64)
-		if !ok {
-			return ErrInvalidOut
+// merge merges src into dst and returns the result. If both are Objects
+// src keys are merged into dst recursively, deleting keys whose src value is
+// null unless keepNull is true. Otherwise a copy of src is returned.
+func merge(dst, src interface{}, keepNull bool) interface{} {
+
+	srcm, ok := src.(map[string]interface{})
+	if !ok {
+		return clone(src)
+	}
+	dstm, ok := dst.(map[string]interface{})
+	if !ok {
+		dstm = make(map[string]interface{}, len(srcm))
+	}
+	for key, val := range srcm {
+		if val == nil && !keepNull {
+			delete(dstm, key)
+			continue
 		}
-		out.Set(reflect.ValueOf(v))
+		dstm[key] = merge(dstm[key], val, keepNull)
+	}
+	return dstm
+}
 
-	// The rest casts the value to type of the output
-	// variable and checks for rounding errors.
+// MergeDefaults adds keys of the Object of other to the Object of the JSON
+// where they do not already exist, recursing into Objects present in both,
+// so that existing values, including nulls, are never overwritten. Added
+// values are copies. It is the counterpart of Layer for filling in defaults.
+// If other is nil returns ErrInvalidIn, if the root of either is not an
+// Object returns ErrTypeMissmatch.
+func (j *JSON) MergeDefaults(other *JSON) error {
 
-	case reflect.Float32:
-		v, ok := in.Interface().(float64)
-		if !ok {
-			return ErrInvalidOut
-		}
-		if v-float64(float32(v)) != 0 {
-			return ErrTruncate
-		}
-		out.Set(reflect.ValueOf(float32(v)))
-	case reflect.Int:
-		v, ok := in.Interface().(float64)
-		if !ok {
-			return ErrInvalidOut
-		}
-		if v-float64(int(v)) != 0 {
-			return ErrTruncate
-		}
-		out.Set(reflect.ValueOf(int(v)))
-	case reflect.Int8:
-		v, ok := in.Interface().(float64)
+	if other == nil {
+		return ErrInvalidIn
+	}
+	dst, ok := j.intf.(map[string]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+	src, ok := other.intf.(map[string]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+	mergeDefaults(dst, src)
+
+	return nil
+}
+
+// mergeDefaults adds copies of src keys absent from dst to dst, recursing
+// into Objects present in both.
+func mergeDefaults(dst, src map[string]interface{}) {
+
+	for key, val := range src {
+		cur, ok := dst[key]
 		if !ok {
-			return ErrInvalidOut
+			dst[key] = clone(val)
+			continue
 		}
-		if v-float64(int8(v)) != 0 {
-			return ErrTruncate
-		}
-		out.Set(reflect.ValueOf(int8(v)))
-	case reflect.Int16:
-		v, ok := in.Interface().(float64)
+		curm, ok := cur.(map[string]interface{})
 		if !ok {
-			return ErrInvalidOut
+			continue
 		}
-		if v-float64(int16(v)) != 0 {
-			return ErrTruncate
+		if valm, ok := val.(map[string]interface{}); ok {
+			mergeDefaults(curm, valm)
 		}
-		out.Set(reflect.ValueOf(int16(v)))
-	case reflect.Int32:
-		v, ok := in.Interface().(float64)
-		if !ok {
-			return ErrInvalidOut
+	}
+}
+
+// FromStruct constructs a new JSON from any Go value v encodable by the json
+// package. Returns a nil JSON and the encoding error if v is not encodable,
+// *JSON otherwise.
+//
+// The JSON holds a copy of v converted to the generic form produced by
+// Unmarshal and does not keep v itself, so changes to either are not
+// reflected in the other and Get pays the usual cost of converting values
+// back. Documents read many times through a known structure are better
+// decoded into that structure directly with the json package, keeping
+// dynamic parts as json.RawMessage fields to be unmarshaled into a JSON when
+// needed.
+func FromStruct(v interface{}) (*JSON, error) {
+
+	ifc, err := normalize(v)
+	if err != nil {
+		return nil, err
+	}
+	return &JSON{intf: ifc}, nil
+}
+
+// NewObject returns a new JSON whose root element is an empty Object.
+func NewObject() *JSON {
+	return &JSON{intf: map[string]interface{}{}}
+}
+
+// NewArray returns a new JSON whose root element is an empty Array.
+func NewArray() *JSON {
+	return &JSON{intf: []interface{}{}}
+}
+
+// find looks for a child element in the JSON using the specified path and
+// returns the key Value with the applicable type that adresses it in its
+// container (be it map or slice), the value itself it as an interface and
+// a nil error on success. It returns just the error if one occured.
+func (j *JSON) find(path Path, parent bool) (reflect.Value, interface{}, error) {
+
+	parentKey := reflect.ValueOf(nil)
+	if parent && len(path) == 0 {
+		return parentKey, nil, ErrInvalidPath
+	}
+
+	result := j.intf // Updated through the loop and returned after it.
+	last := len(path) - 1
+	for i, seg := range path {
+
+		if seg.Wildcard || seg.Append {
+			return parentKey, nil, ErrInvalidPath
 		}
-		if v-float64(int32(v)) != 0 {
-			return ErrTruncate
+
+		if seg.Array {
+			si, ok := result.([]interface{})
+			if !ok {
+				return parentKey, nil, newPathError(path, i, ErrNotFound)
+			}
+			if seg.Index < 0 || seg.Index >= len(si) {
+				return parentKey, nil, newPathError(path, i, ErrOutOfRange)
+			}
+			if parent && i == last {
+				return reflect.ValueOf(seg.Index), si, nil
+			}
+			result = si[seg.Index]
+			continue
 		}
-		out.Set(reflect.ValueOf(int32(v)))
-	case reflect.Int64:
-		v, ok := in.Interface().(float64)
+
+		mi, ok := result.(map[string]interface{})
 		if !ok {
-			return ErrInvalidOut
+			return parentKey, nil, newPathError(path, i, ErrNotFound)
 		}
-		if v-float64(int64(v)) != 0 {
-			return ErrTruncate
+		if parent && i == last {
+			return reflect.ValueOf(seg.Key), mi, nil
 		}
-		out.Set(reflect.ValueOf(int64(v)))
-	case reflect.Uint:
-		v, ok := in.Interface().(float64)
+		iv, ok := mi[seg.Key]
 		if !ok {
-			return ErrInvalidOut
-		}
-		if v-float64(uint(v)) != 0 {
-			return ErrTruncate
+			return parentKey, nil, newPathError(path, i, ErrNotFound)
 		}
-		out.Set(reflect.ValueOf(uint(v)))
-	case reflect.Uint8:
-		v, ok := in.Interface().(float64)
-		if !ok {
-			return ErrInvalidOut
-		}
-		if v-float64(uint8(v)) != 0 {
-			return ErrTruncate
+		result = iv
+	}
+
+	return parentKey, result, nil
+}
+
+// get returns the value of the element under path. An empty path
+// addresses the root element.
+func (j *JSON) get(path string) (interface{}, error) {
+
+	p, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+	return j.getPath(p)
+}
+
+// getPath is get with a parsed Path.
+func (j *JSON) getPath(path Path) (interface{}, error) {
+
+	_, ifc, err := j.find(path, false)
+	if err != nil {
+		return nil, err
+	}
+	return ifc, nil
+}
+
+// put stores v as the value of the element under path. v must be in the
+// form produced by normalize. An empty path addresses the root element.
+func (j *JSON) put(path string, v interface{}) error {
+
+	p, err := parse(path)
+	if err != nil {
+		return err
+	}
+	return j.putPath(p, v)
+}
+
+// putPath is put with a parsed Path.
+func (j *JSON) putPath(path Path, v interface{}) error {
+
+	if len(path) == 0 {
+		j.intf = v
+		return nil
+	}
+
+	key, tgt, err := j.find(path, true)
+	if err != nil {
+		return err
+	}
+
+	tgtval := reflect.ValueOf(tgt)
+	switch tgtval.Kind() {
+	case reflect.Map:
+		tgtval.SetMapIndex(key, reflect.ValueOf(&v).Elem())
+	case reflect.Slice:
+		i := int(key.Int())
+		if i < 0 || i >= tgtval.Len() {
+			return newPathError(path, len(path)-1, ErrOutOfRange)
 		}
-		out.Set(reflect.ValueOf(uint8(v)))
-	case reflect.Uint16:
-		v, ok := in.Interface().(float64)
-		if !ok {
-			return ErrInvalidOut
+		tgtval.Index(i).Set(reflect.ValueOf(&v).Elem())
+	default:
+		return ErrTypeMissmatch
+	}
+
+	return nil
+}
+
+// putDeep is like putPath but creates missing Objects and Arrays on path. A
+// missing or null element followed by a key segment is created as an Object
+// and one followed by an index segment as an Array padded with nulls up to
+// the index. Existing Arrays are not extended except by append segments which
+// append a new element to an existing or created Array.
+func (j *JSON) putDeep(path Path, v interface{}) error {
+
+	for _, seg := range path {
+		if seg.Wildcard {
+			return ErrInvalidPath
 		}
-		if v-float64(uint16(v)) != 0 {
-			return ErrTruncate
+	}
+	return putDeep(&j.intf, path, 0, v)
+}
+
+// putDeep stores v under path[i:] relative to node for JSON.putDeep.
+func putDeep(node *interface{}, path Path, i int, v interface{}) error {
+
+	if i == len(path) {
+		*node = v
+		return nil
+	}
+
+	seg := path[i]
+	if seg.Append {
+		if *node == nil {
+			*node = []interface{}{}
 		}
-		out.Set(reflect.ValueOf(uint16(v)))
-	case reflect.Uint32:
-		v, ok := in.Interface().(float64)
+		slc, ok := (*node).([]interface{})
 		if !ok {
-			return ErrInvalidOut
+			return newPathError(path, i, ErrNotFound)
 		}
-		if v-float64(uint32(v)) != 0 {
-			return ErrTruncate
+		slc = append(slc, nil)
+		if err := putDeep(&slc[len(slc)-1], path, i+1, v); err != nil {
+			return err
 		}
-		out.Set(reflect.ValueOf(uint32(v)))
-	case reflect.Uint64:
-		v, ok := in.Interface().(float64)
+		*node = slc
+		return nil
+	}
+	if seg.Array {
+		if *node == nil {
+			*node = make([]interface{}, seg.Index+1)
+		}
+		slc, ok := (*node).([]interface{})
 		if !ok {
-			return ErrInvalidOut
+			return newPathError(path, i, ErrNotFound)
 		}
-		if v-float64(uint64(v)) != 0 {
-			return ErrTruncate
+		if seg.Index >= len(slc) {
+			return newPathError(path, i, ErrOutOfRange)
 		}
-		out.Set(reflect.ValueOf(uint64(v)))
+		return putDeep(&slc[seg.Index], path, i+1, v)
 	}
 
+	if *node == nil {
+		*node = map[string]interface{}{}
+	}
+	m, ok := (*node).(map[string]interface{})
+	if !ok {
+		return newPathError(path, i, ErrNotFound)
+	}
+	child := m[seg.Key]
+	if err := putDeep(&child, path, i+1, v); err != nil {
+		return err
+	}
+	m[seg.Key] = child
 	return nil
 }
 
-// Get gets a JSON value by path and writes it to out. If path is malformed
-// returns ErrInvalidPath. If path specifies a non-existent element returns
-// ErrNotFound. If out is not a pointer to a variable of a type compatible
-// with the specified element value returns ErrInvalidTarget.
-//
-// It will try to assign a Number element into any type of numeric type
-// including ints, uints floats and custom types with basic numeral base
-// types. If the value can be assigned to out but causes the value to
-// overflow or truncate function returns ErrTruncate.
-//
-// When assigning an Object to a struct only fields present in both input and
-// settable output are assigned. Field names are matched first by tag
-// respecting case, then by field name ignoring case. In other words, just
-// like json package. Non-matched fields are silently skipped, meaning, you
-// could end up with an empty struct without any errors.
-//
-// On success function returns nil.
-func (j *JSON) Get(path string, out interface{}) error {
+// remove removes the element under path from its parent Object or Array.
+func (j *JSON) remove(path string) error {
 
-	outv := reflect.ValueOf(out)
-	if !outv.IsValid() || outv.Kind() != reflect.Ptr {
-		return ErrInvalidOut
+	p, err := parse(path)
+	if err != nil {
+		return err
 	}
-	outv = outv.Elem()
+	return j.removePath(p)
+}
 
-	_, ifc, err := j.find(path, false)
+// removePath is remove with a parsed Path.
+func (j *JSON) removePath(path Path) error {
+
+	key, tgt, err := j.find(path, true)
 	if err != nil {
 		return err
 	}
-	inv := reflect.ValueOf(ifc)
 
-	return j.assign(inv, outv)
+	switch t := tgt.(type) {
+	case map[string]interface{}:
+		if _, ok := t[key.String()]; !ok {
+			return newPathError(path, len(path)-1, ErrNotFound)
+		}
+		delete(t, key.String())
+	case []interface{}:
+		i := int(key.Int())
+		return j.putPath(path[:len(path)-1], append(t[:i:i], t[i+1:]...))
+	}
+
+	return nil
 }
 
-// Set sets a JSON element value by path. If path is malformed returns
-// ErrInvalidPath. Set forces the full path of an element and the element
-// itself discarding any overwritten entries without notice.
-// On success function returns nil.
-func (j *JSON) Set(path string, in interface{}) error {
+// insert inserts v into the Array under path at index shifting subsequent
+// elements right. An index equal to the Array length appends v.
+func (j *JSON) insert(path string, index int, v interface{}) error {
 
-	inv := reflect.ValueOf(in)
-	if !inv.IsValid() {
-		return ErrInvalidIn
+	p, err := parse(path)
+	if err != nil {
+		return err
 	}
+	return j.insertPath(p, index, v)
+}
 
-	key, tgt, err := j.find(path, true)
+// insertPath is insert with a parsed Path.
+func (j *JSON) insertPath(path Path, index int, v interface{}) error {
+
+	ifc, err := j.getPath(path)
 	if err != nil {
 		return err
 	}
+	slc, ok := ifc.([]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+	if index < 0 || index > len(slc) {
+		return ErrOutOfRange
+	}
+
+	n := make([]interface{}, 0, len(slc)+1)
+	n = append(n, slc[:index]...)
+	n = append(n, v)
+	n = append(n, slc[index:]...)
+
+	return j.putPath(path, n)
+}
+
+// normalize converts in to the form produced by unmarshaling JSON into an
+// interface by encoding it to JSON and decoding it back.
+func normalize(in interface{}) (interface{}, error) {
 
 	buff := bytes.NewBuffer(nil)
 	enc := json.NewEncoder(buff)
 	if err := enc.Encode(in); err != nil {
-		return err
+		return nil, err
 	}
 	var ifc interface{}
 	if err := json.Unmarshal(buff.Bytes(), &ifc); err != nil {
-		return err
+		return nil, err
 	}
+	return ifc, nil
+}
 
-	tgtval := reflect.ValueOf(tgt)
-	switch tgtval.Kind() {
-	case reflect.Map:
-		tgtval.SetMapIndex(key, reflect.ValueOf(ifc))
-	case reflect.Slice:
-		tgtval.Index(int(key.Int())).Set(reflect.ValueOf(ifc))
-	default:
-		panic("this shouldn't happen: parent value not map or slice")
+// normalizeUseNumber is like normalize but keeps Numbers as json.Number so
+// that integers can be compared with values of the JSON without losing
+// precision.
+func normalizeUseNumber(in interface{}) (interface{}, error) {
+
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
 	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var ifc interface{}
+	if err := dec.Decode(&ifc); err != nil {
+		return nil, err
+	}
+	return ifc, nil
+}
 
-	return nil
+// clone returns a deep copy of ifc which must be in the form produced by
+// normalize.
+func clone(ifc interface{}) interface{} {
+
+	switch v := ifc.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[key] = clone(val)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, val := range v {
+			s[i] = clone(val)
+		}
+		return s
+	}
+	return ifc
 }
 
-// Len returns the length of the Array specified by path. If path is malformed
-// returns ErrInvalidPath. If Array is not found returns ErrNotFound. Returns
-// the Array length on success or -1 and an error otherwise.
-func (j *JSON) Len(path string) (int, error) {
+var (
+	// timeType is the reflect.Type of time.Time.
+	timeType = reflect.TypeOf(time.Time{})
 
-	_, slc, err := j.find(path, false)
-	if err != nil {
-		return -1, err
+	// jsonType is the reflect.Type of JSON.
+	jsonType = reflect.TypeOf(JSON{})
+
+	// numberType is the reflect.Type of json.Number.
+	numberType = reflect.TypeOf(json.Number(""))
+)
+
+// assign recursively assigns in to out in a manner defined by this JSON type.
+func (j *JSON) assign(in, out reflect.Value) error {
+
+	if in.Kind() == reflect.Interface {
+		in = in.Elem()
 	}
-	slcv, ok := slc.([]interface{})
-	if !ok {
-		return -1, ErrInvalidPath
+	if !in.IsValid() {
+		switch out.Kind() {
+		case reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+			out.Set(reflect.Zero(out.Type()))
+		}
+		return nil
 	}
-	return len(slcv), nil
-}
 
-// Export exports the JSON in its' current state as a slice of bytes.
-func (j *JSON) Export(indent string) ([]byte, error) {
+	if out.Type() == jsonType {
+		out.Set(reflect.ValueOf(JSON{intf: clone(in.Interface()), tag: j.tag}))
+		return nil
+	}
 
-	var b []byte
-	var err error
-	if indent != "" {
-		b, err = json.MarshalIndent(j.intf, "", indent)
-	} else {
-		b, err = json.Marshal(j.intf)
+	n, isNumber := in.Interface().(json.Number)
+	if isNumber {
+		if out.Type() == numberType {
+			out.Set(in)
+			return nil
+		}
+		switch out.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+				if out.OverflowInt(i) {
+					return ErrTruncate
+				}
+				out.SetInt(i)
+				return nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+				if out.OverflowUint(u) {
+					return ErrTruncate
+				}
+				out.SetUint(u)
+				return nil
+			}
+		}
 	}
-	if err != nil {
-		return nil, err
+
+	if out.Type() == timeType {
+		switch v := in.Interface().(type) {
+		case string:
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return err
+			}
+			out.Set(reflect.ValueOf(t))
+		case float64, json.Number:
+			f, _ := number(v)
+			sec := math.Floor(f)
+			out.Set(reflect.ValueOf(time.Unix(int64(sec), int64(math.Round((f-sec)*1e9))).UTC()))
+		default:
+			return ErrInvalidOut
+		}
+		return nil
+	}
+
+	if out.CanAddr() {
+		if u, ok := out.Addr().Interface().(json.Unmarshaler); ok {
+			b, err := json.Marshal(in.Interface())
+			if err != nil {
+				return err
+			}
+			return u.UnmarshalJSON(b)
+		}
+	}
+
+	// A json.Number is kept for interfaces and pointers which will receive
+	// it as is or recurse with it, and otherwise converted to a float64.
+	if isNumber && out.Kind() != reflect.Interface && out.Kind() != reflect.Ptr {
+		f, err := n.Float64()
+		if err != nil {
+			return err
+		}
+		in = reflect.ValueOf(f)
+	}
+
+	if in.Kind() == reflect.String && out.CanAddr() {
+		if u, ok := out.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(in.String()))
+		}
+	}
+
+	if j.lenient && in.Kind() == reflect.String {
+		switch out.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			f, err := strconv.ParseFloat(in.String(), 64)
+			if err != nil {
+				return err
+			}
+			in = reflect.ValueOf(f)
+		}
+	}
+
+	if f, ok := in.Interface().(float64); ok && j.trunc {
+		switch out.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			in = reflect.ValueOf(math.Trunc(f))
+		case reflect.Float32:
+			in = reflect.ValueOf(float64(float32(f)))
+		}
+	}
+
+	switch out.Kind() {
+
+	case reflect.Interface:
+		if !in.Type().AssignableTo(out.Type()) {
+			return ErrTypeMissmatch
+		}
+		out.Set(in)
+
+	case reflect.Ptr:
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		return j.assign(in, out.Elem())
+
+	case reflect.Map:
+		if in.Kind() != reflect.Map {
+			return ErrTypeMissmatch
+		}
+		m := reflect.MakeMapWithSize(out.Type(), in.Len())
+		for _, key := range in.MapKeys() {
+			k := reflect.New(out.Type().Key()).Elem()
+			if err := assignKey(key.String(), k); err != nil {
+				return err
+			}
+			v := reflect.New(out.Type().Elem()).Elem()
+			if err := j.assign(in.MapIndex(key), v); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, v)
+		}
+		out.Set(m)
+
+	case reflect.Slice:
+		if out.Type().Elem().Kind() == reflect.Uint8 && in.Kind() == reflect.String {
+			b, err := base64.StdEncoding.DecodeString(in.String())
+			if err != nil {
+				return err
+			}
+			out.SetBytes(b)
+			return nil
+		}
+		if in.Kind() != reflect.Slice {
+			return ErrTypeMissmatch
+		}
+		sl := reflect.MakeSlice(out.Type(), in.Len(), in.Len())
+		for i := 0; i < in.Len(); i++ {
+			if err := j.assign(in.Index(i), sl.Index(i)); err != nil {
+				return err
+			}
+		}
+		out.Set(sl)
+
+	case reflect.Array:
+		if in.Kind() != reflect.Slice {
+			return ErrTypeMissmatch
+		}
+		for i := 0; i < out.Len(); i++ {
+			if i >= in.Len() {
+				out.Index(i).Set(reflect.Zero(out.Type().Elem()))
+				continue
+			}
+			if err := j.assign(in.Index(i), out.Index(i)); err != nil {
+				return err
+			}
+		}
+		if in.Len() > out.Len() {
+			return ErrTruncate
+		}
+
+	case reflect.Struct:
+		if in.Kind() != reflect.Map {
+			return ErrTypeMissmatch
+		}
+		if j.closed {
+			if err := j.checkClosed(in, out.Type()); err != nil {
+				return err
+			}
+		}
+		if j.unknown != nil {
+			for _, key := range in.MapKeys() {
+				if !j.hasField(out.Type(), key.String(), map[reflect.Type]bool{}) {
+					*j.unknown = append(*j.unknown, key.String())
+				}
+			}
+		}
+		return j.assignStruct(in, out, map[reflect.Type]bool{})
+
+	// Booleans, Strings and Numbers are directly
+	// assigned as json package defines.
+
+	case reflect.Bool:
+		v, ok := in.Interface().(bool)
+		if !ok {
+			return ErrInvalidOut
+		}
+		out.SetBool(v)
+	case reflect.String:
+		v, ok := in.Interface().(string)
+		if !ok {
+			return ErrInvalidOut
+		}
+		out.SetString(v)
+	case reflect.Float64:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		out.SetFloat(v)
+
+	// The rest casts the value to type of the output
+	// variable and checks for rounding errors.
+
+	case reflect.Float32:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		if v-float64(float32(v)) != 0 {
+			return ErrTruncate
+		}
+		out.SetFloat(float64(float32(v)))
+	case reflect.Int:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		if v-float64(int(v)) != 0 {
+			return ErrTruncate
+		}
+		out.SetInt(int64(int(v)))
+	case reflect.Int8:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		if v-float64(int8(v)) != 0 {
+			return ErrTruncate
+		}
+		out.SetInt(int64(int8(v)))
+	case reflect.Int16:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		if v-float64(int16(v)) != 0 {
+			return ErrTruncate
+		}
+		out.SetInt(int64(int16(v)))
+	case reflect.Int32:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		if v-float64(int32(v)) != 0 {
+			return ErrTruncate
+		}
+		out.SetInt(int64(int32(v)))
+	case reflect.Int64:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		if v-float64(int64(v)) != 0 {
+			return ErrTruncate
+		}
+		out.SetInt(int64(v))
+	case reflect.Uint:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		if v-float64(uint(v)) != 0 {
+			return ErrTruncate
+		}
+		out.SetUint(uint64(uint(v)))
+	case reflect.Uint8:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		if v-float64(uint8(v)) != 0 {
+			return ErrTruncate
+		}
+		out.SetUint(uint64(uint8(v)))
+	case reflect.Uint16:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		if v-float64(uint16(v)) != 0 {
+			return ErrTruncate
+		}
+		out.SetUint(uint64(uint16(v)))
+	case reflect.Uint32:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		if v-float64(uint32(v)) != 0 {
+			return ErrTruncate
+		}
+		out.SetUint(uint64(uint32(v)))
+	case reflect.Uint64:
+		v, ok := in.Interface().(float64)
+		if !ok {
+			return ErrInvalidOut
+		}
+		if v-float64(uint64(v)) != 0 {
+			return ErrTruncate
+		}
+		out.SetUint(uint64(v))
+	default:
+		return ErrInvalidOut
+	}
+
+	return nil
+}
+
+// assignStruct assigns Object in to struct out. Keys not matched by fields of
+// out are assigned to structs embedded in out, skipping embedded struct types
+// in visited to break embedding cycles.
+func (j *JSON) assignStruct(in, out reflect.Value, visited map[reflect.Type]bool) error {
+
+	visited[out.Type()] = true
+	keys := in.MapKeys()
+	matched := make(map[string]bool, len(keys))
+	embedded := []int{}
+	inline := -1
+	for i := 0; i < out.NumField(); i++ {
+
+		fld := out.Type().Field(i)
+		if isEmbedded(fld, j.tag) {
+			embedded = append(embedded, i)
+			continue
+		}
+		if !out.Field(i).CanSet() {
+			continue
+		}
+		jtags := strings.Split(fld.Tag.Get("jsonobj"), ",")
+		if strings.Contains(jtags[0], ":") {
+			if err := j.aggregate(in, jtags[0], out.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if isInline(fld) {
+			inline = i
+			continue
+		}
+
+		for k := 0; k < len(keys); k++ {
+			if !j.matchField(keys[k].String(), fld) {
+				continue
+			}
+			matched[keys[k].String()] = true
+			val := in.MapIndex(keys[k])
+			if hasOption(jtags[1:], "closed") {
+				if err := j.checkClosed(val, fld.Type); err != nil {
+					return err
+				}
+			}
+			if err := j.assign(val, out.Field(i)); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	// Fields of embedded structs are shadowed by fields of out.
+	rest := make(map[string]interface{}, len(keys)-len(matched))
+	for _, key := range keys {
+		if !matched[key.String()] {
+			rest[key.String()] = in.MapIndex(key).Interface()
+		}
+	}
+	for _, i := range embedded {
+		fv := out.Field(i)
+		et := fv.Type()
+		if et.Kind() == reflect.Ptr {
+			et = et.Elem()
+		}
+		if visited[et] || !j.hasAnyField(et, rest, visited) {
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			if !fv.CanSet() {
+				continue
+			}
+			if fv.IsNil() {
+				fv.Set(reflect.New(et))
+			}
+			fv = fv.Elem()
+		}
+		if err := j.assignStruct(reflect.ValueOf(rest), fv, visited); err != nil {
+			return err
+		}
+	}
+
+	if inline < 0 {
+		return nil
+	}
+	extra := make(map[string]interface{}, len(rest))
+	for key, val := range rest {
+		claimed := false
+		for _, i := range embedded {
+			et := out.Type().Field(i).Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if claimed = j.hasField(et, key, map[reflect.Type]bool{out.Type(): true}); claimed {
+				break
+			}
+		}
+		if !claimed {
+			extra[key] = val
+		}
+	}
+	if len(extra) == 0 {
+		return nil
+	}
+	return j.assign(reflect.ValueOf(extra), out.Field(inline))
+}
+
+// isInline returns true if fld is a map field tagged with the "inline" option
+// of the jsonobj tag.
+func isInline(fld reflect.StructField) bool {
+	return fld.Type.Kind() == reflect.Map &&
+		hasOption(strings.Split(fld.Tag.Get("jsonobj"), ",")[1:], "inline")
+}
+
+// isEmbedded returns true if fld is an embedded struct or pointer to struct
+// whose fields are promoted, i.e. not given a name in the tag named tag.
+func isEmbedded(fld reflect.StructField, tag string) bool {
+
+	if !fld.Anonymous {
+		return false
+	}
+	if tag == "" {
+		tag = "json"
+	}
+	if strings.Split(fld.Tag.Get(tag), ",")[0] != "" {
+		return false
+	}
+	t := fld.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// hasAnyField returns true if any key of m is matched by a field of struct
+// type t or of structs embedded in it, skipping types in visited.
+func (j *JSON) hasAnyField(t reflect.Type, m map[string]interface{}, visited map[reflect.Type]bool) bool {
+
+	for key := range m {
+		if j.hasField(t, key, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasField returns true if key is matched by a field of struct type t or of
+// structs embedded in it that Get would assign it to, skipping embedded
+// struct types in visited.
+func (j *JSON) hasField(t reflect.Type, key string, visited map[reflect.Type]bool) bool {
+
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if isEmbedded(fld, j.tag) {
+			et := fld.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if visited[et] || et == t {
+				continue
+			}
+			visited[et] = true
+			found := j.hasField(et, key, visited)
+			delete(visited, et)
+			if found {
+				return true
+			}
+			continue
+		}
+		if fld.PkgPath != "" ||
+			strings.Contains(strings.Split(fld.Tag.Get("jsonobj"), ",")[0], ":") {
+			continue
+		}
+		if isInline(fld) {
+			return true
+		}
+		if j.matchField(key, fld) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchField returns true if Object key matches struct field fld. Key is
+// matched to the name given in the tag named j.tag, "json" if empty,
+// respecting case if one is specified, otherwise to the field name by
+// j.matcher or, if nil, ignoring case. Fields tagged with "-" match no keys.
+func (j *JSON) matchField(key string, fld reflect.StructField) bool {
+
+	tag := j.tag
+	if tag == "" {
+		tag = "json"
+	}
+	name := strings.Split(fld.Tag.Get(tag), ",")[0]
+	if name == "-" {
+		return false
+	}
+	if name != "" {
+		return key == name
+	}
+	if j.matcher != nil {
+		return j.matcher(key, fld.Name)
+	}
+	return strings.EqualFold(key, fld.Name)
+}
+
+// hasOption returns true if opts contains opt.
+func hasOption(opts []string, opt string) bool {
+
+	for _, v := range opts {
+		if v == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// checkClosed returns an *UnknownFieldError if Object in contains a key that
+// is not matched by any field of struct type t that Get would assign it to.
+// Pointer types are dereferenced to the struct type they point to. Keys are
+// checked in sorted order.
+func (j *JSON) checkClosed(in reflect.Value, t reflect.Type) error {
+
+	if in.Kind() == reflect.Interface {
+		in = in.Elem()
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if in.Kind() != reflect.Map || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	keys := make([]string, 0, in.Len())
+	for _, key := range in.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !j.hasField(t, key, map[reflect.Type]bool{}) {
+			return &UnknownFieldError{Key: key}
+		}
+	}
+
+	return nil
+}
+
+// assignKey assigns an Object key to out converting it to the type of out
+// which must be a string or an integer kind. If key cannot be converted
+// returns ErrTypeMissmatch.
+func assignKey(key string, out reflect.Value) error {
+
+	switch out.Kind() {
+	case reflect.String:
+		out.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, out.Type().Bits())
+		if err != nil {
+			return ErrTypeMissmatch
+		}
+		out.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(key, 10, out.Type().Bits())
+		if err != nil {
+			return ErrTypeMissmatch
+		}
+		out.SetUint(n)
+	default:
+		return ErrInvalidOut
+	}
+
+	return nil
+}
+
+// aggregate assigns an aggregate of Numbers in the Object in to out. The
+// aggregation is specified as "func:pattern" where func is one of "sum",
+// "avg", "count", "min" or "max" and pattern is a wildcard path relative
+// to in as accepted by Stats. If pattern matches no Numbers out is left
+// untouched.
+func (j *JSON) aggregate(in reflect.Value, agg string, out reflect.Value) error {
+
+	a := strings.Index(agg, ":")
+	st, err := (&JSON{intf: in.Interface()}).Stats(agg[a+1:])
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var v float64
+	switch agg[:a] {
+	case "sum":
+		v = st.Sum
+	case "avg":
+		v = st.Mean
+	case "count":
+		v = float64(st.Count)
+	case "min":
+		v = st.Min
+	case "max":
+		v = st.Max
+	default:
+		return ErrInvalidTag
+	}
+
+	return j.assign(reflect.ValueOf(v), out)
+}
+
+// Get gets a JSON value by path and writes it to out. If path is malformed
+// returns ErrInvalidPath. If path specifies a non-existent element returns
+// ErrNotFound. If out is not a pointer to a variable of a type compatible
+// with the specified element value returns ErrInvalidTarget. Errors resolving
+// path are returned as a *PathError wrapping ErrNotFound or ErrOutOfRange;
+// use errors.Is to test for them.
+//
+// It will try to assign a Number element into any type of numeric type
+// including ints, uints floats and custom types with basic numeral base
+// types. If the value can be assigned to out but causes the value to
+// overflow or truncate function returns ErrTruncate.
+//
+// When assigning an Object to a struct only fields present in both input and
+// settable output are assigned. Field names are matched first by tag
+// respecting case, then by field name ignoring case. In other words, just
+// like json package. Non-matched fields are silently skipped, meaning, you
+// could end up with an empty struct without any errors.
+//
+// Fields of embedded structs without a name in their tag are promoted to the
+// embedding struct; Object keys not matched by fields of the embedding
+// struct are matched against fields of embedded structs, so an outer field
+// shadows an embedded field of the same name.
+//
+// A struct field of struct or struct pointer type tagged with
+// `jsonobj:",closed"` does not tolerate keys of its Object which match none
+// of its fields; Get returns an *UnknownFieldError wrapping ErrUnknownField
+// if it encounters one.
+//
+// An Object can be assigned to a map with string or integer keys. Object keys
+// are converted to the key type, if a key cannot be converted returns
+// ErrTypeMissmatch. Assigning a non-Object to a map returns ErrTypeMissmatch.
+// A map[string]interface{} receives a shallow copy of the Object: the map
+// itself is new but Objects and Arrays stored in it are shared with the JSON
+// as with interface outputs.
+//
+// A String holding an RFC 3339 timestamp can be assigned to a time.Time. If
+// the String cannot be parsed the parse error is returned. A Number assigned
+// to a time.Time is taken as a Unix timestamp in seconds, with the fraction
+// giving sub-second precision, and yields a time in UTC. Timestamps in other
+// units, i.e. milliseconds, should be read into an integer and converted.
+//
+// A String is assigned to a type implementing encoding.TextUnmarshaler, with
+// either value or pointer receiver, by calling its UnmarshalText method. An
+// error returned by UnmarshalText is returned as is.
+//
+// A type implementing json.Unmarshaler is given the value encoded back to
+// JSON by calling its UnmarshalJSON method, which takes precedence over
+// UnmarshalText. As this requires encoding the value it is done only for
+// such types. This includes json.RawMessage which receives the value encoded
+// as compact JSON, allowing decoding of parts of the document to be deferred.
+//
+// Any value can be assigned to a JSON, including a JSON struct field or a
+// pointer to one, which receives a copy of the value as its root element so
+// that handling of dynamic parts of a document can be deferred.
+//
+// An Array can be assigned to a Go array. If the Array is shorter remaining
+// elements of the Go array are set to zero values, if it is longer the Go
+// array is filled and ErrTruncate is returned.
+//
+// A String is assigned to a []byte by decoding it as standard base64, which
+// is how the json package encodes byte slices. If the String is not valid
+// base64 the decoding error is returned.
+//
+// Pointers, including pointer struct fields and pointer slice elements, are
+// allocated if nil and the value is assigned to the variable they point to.
+// Null slice elements are left as nil pointers.
+//
+// A null value sets an out of interface, map, pointer or slice type to nil
+// and leaves out of any other type untouched. Fields of a struct for which
+// the Object has no key are always left untouched. To tell a null from an
+// absent element use IsNull.
+//
+// If out is an interface the value is assigned as is, as a bool, float64,
+// string, []interface{} or map[string]interface{}. Objects and Arrays
+// assigned this way are shared with the JSON, not copied. If the value does
+// not implement a non-empty interface returns ErrTypeMissmatch.
+//
+// A map field tagged with `jsonobj:",inline"` catches all keys of the Object
+// that match no other field of the struct, including fields of embedded
+// structs, so that no data is lost. It is left untouched if there are no
+// such keys. Keys caught this way are not considered unknown.
+//
+// A struct field tagged with `jsonobj:"func:pattern"` is not matched by name
+// but receives an aggregate of Numbers matched by the wildcard pattern
+// relative to the Object being assigned, where func is one of "sum", "avg",
+// "count", "min" or "max", i.e. `jsonobj:"sum:items[*].price"`. If an
+// unknown func is specified returns ErrInvalidTag.
+//
+// Path may contain "[*]" wildcards in place of Array indexes in which case
+// values of all matched elements are collected into out which must be a
+// pointer to a slice, otherwise returns ErrInvalidOut. For example to get
+// names of all planets:
+//
+//	var names []string
+//	jf.Get("planets[*].name", &names)
+//
+// Elements that do not contain the rest of the path after a wildcard are
+// skipped.
+//
+// If out is of a kind no JSON value can be assigned to, i.e. a channel,
+// function or complex number, returns ErrInvalidOut.
+//
+// On success function returns nil.
+func (j *JSON) Get(path string, out interface{}) error {
+
+	p, err := parse(path)
+	if err != nil {
+		return err
+	}
+	return j.GetPath(p, out)
+}
+
+// GetPath is like Get but takes a Path parsed by ParsePath.
+func (j *JSON) GetPath(path Path, out interface{}) error {
+
+	outv := reflect.ValueOf(out)
+	if !outv.IsValid() || outv.Kind() != reflect.Ptr || outv.IsNil() {
+		return ErrInvalidOut
+	}
+	outv = outv.Elem()
+
+	if path.HasWildcard() {
+		if outv.Kind() != reflect.Slice {
+			return ErrInvalidOut
+		}
+		values, err := j.match(path)
+		if err != nil {
+			return err
+		}
+		return j.assign(reflect.ValueOf(values), outv)
+	}
+
+	ifc, err := j.getPath(path)
+	if err != nil {
+		return err
+	}
+	inv := reflect.ValueOf(ifc)
+
+	return j.assign(inv, outv)
+}
+
+// GetWithMatcher is like Get but matches Object keys to names of struct
+// fields which have no name given in their tag using match instead of
+// comparing them ignoring case, i.e. to map snake_case keys to CamelCase
+// field names. Match is called with the Object key and the field name.
+// Fields with a name given in their tag are still matched by that name.
+func (j *JSON) GetWithMatcher(path string, out interface{}, match func(jsonKey, fieldName string) bool) error {
+	return (&JSON{intf: j.intf, tag: j.tag, matcher: match}).Get(path, out)
+}
+
+// GetLenient is like Get but also assigns Strings holding numbers, i.e.
+// "42", to numeric outputs as if they were Numbers. If such a String cannot
+// be parsed as a number the parse error is returned.
+func (j *JSON) GetLenient(path string, out interface{}) error {
+	return (&JSON{intf: j.intf, tag: j.tag, lenient: true}).Get(path, out)
+}
+
+// GetTruncate is like Get but assigns Numbers with a fraction to integer
+// outputs by truncating them toward zero instead of returning ErrTruncate,
+// i.e. 3.9 yields 3 and -3.9 yields -3. Numbers assigned to float32 outputs
+// are rounded to the nearest float32. Numbers outside the range of an integer
+// output still return ErrTruncate.
+func (j *JSON) GetTruncate(path string, out interface{}) error {
+	return (&JSON{intf: j.intf, tag: j.tag, trunc: true}).Get(path, out)
+}
+
+// GetPositional assigns elements of the Array under path to fields of the
+// struct out points to by position: the first element to the first field,
+// the second to the second and so on, in order of declaration. Unexported
+// fields and fields tagged with "-" are skipped. Surplus elements are ignored
+// and fields without a corresponding element are left untouched. Elements
+// are assigned to fields as by Get. If out is not a pointer to a struct
+// returns ErrInvalidOut. If the element under path is not an Array returns
+// ErrTypeMissmatch.
+func (j *JSON) GetPositional(path string, out interface{}) error {
+
+	outv := reflect.ValueOf(out)
+	if !outv.IsValid() || outv.Kind() != reflect.Ptr || outv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidOut
+	}
+	outv = outv.Elem()
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	slc, ok := ifc.([]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+
+	tag := j.tag
+	if tag == "" {
+		tag = "json"
+	}
+	i := 0
+	for f := 0; f < outv.NumField() && i < len(slc); f++ {
+		fld := outv.Type().Field(f)
+		if !outv.Field(f).CanSet() || strings.Split(fld.Tag.Get(tag), ",")[0] == "-" {
+			continue
+		}
+		if err := j.assign(reflect.ValueOf(slc[i]), outv.Field(f)); err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
+
+// GetStrict is like Get but also returns keys of Objects assigned to structs,
+// including nested ones, which were not matched by any struct field and were
+// skipped. Keys are returned in no particular order. The returned slice is
+// empty if all keys were matched.
+func (j *JSON) GetStrict(path string, out interface{}) ([]string, error) {
+
+	unknown := []string{}
+	if err := (&JSON{intf: j.intf, tag: j.tag, unknown: &unknown}).Get(path, out); err != nil {
+		return nil, err
+	}
+	return unknown, nil
+}
+
+// GetDisallowUnknown is like Get but treats every struct as closed: if an
+// Object assigned to a struct, including nested ones, contains a key matched
+// by none of its fields returns an *UnknownFieldError naming the key, which
+// wraps ErrUnknownField.
+func (j *JSON) GetDisallowUnknown(path string, out interface{}) error {
+	return (&JSON{intf: j.intf, tag: j.tag, closed: true}).Get(path, out)
+}
+
+// MustGet is like Get but panics if Get returns an error. The panic message
+// includes the path. It is intended for cases where failure to read a value
+// is a programming error and cannot be recovered from, such as reading
+// constant documents on initialization or in tests.
+func (j *JSON) MustGet(path string, out interface{}) {
+
+	if err := j.Get(path, out); err != nil {
+		panic("jsonobj: MustGet(" + strconv.Quote(path) + "): " + err.Error())
+	}
+}
+
+// MustGetString is like MustGet but returns the value as a string.
+func (j *JSON) MustGetString(path string) string {
+
+	var s string
+	j.MustGet(path, &s)
+	return s
+}
+
+// MustGetInt is like MustGet but returns the value as an int.
+func (j *JSON) MustGetInt(path string) int {
+
+	var n int
+	j.MustGet(path, &n)
+	return n
+}
+
+// GetStringOr returns the String value under path or def if it cannot be
+// read for any reason or is null.
+func (j *JSON) GetStringOr(path, def string) string {
+
+	s := def
+	if err := j.Get(path, &s); err != nil {
+		return def
+	}
+	return s
+}
+
+// GetIntOr returns the Number value under path as an int or def if it
+// cannot be read for any reason, including truncation, or is null.
+func (j *JSON) GetIntOr(path string, def int) int {
+
+	n := def
+	if err := j.Get(path, &n); err != nil {
+		return def
+	}
+	return n
+}
+
+// GetBoolOr returns the Boolean value under path or def if it cannot be read
+// for any reason or is null.
+func (j *JSON) GetBoolOr(path string, def bool) bool {
+
+	b := def
+	if err := j.Get(path, &b); err != nil {
+		return def
+	}
+	return b
+}
+
+// GetFloat64Or returns the Number value under path or def if it cannot be
+// read for any reason or is null.
+func (j *JSON) GetFloat64Or(path string, def float64) float64 {
+
+	f := def
+	if err := j.Get(path, &f); err != nil {
+		return def
+	}
+	return f
+}
+
+// GetBytes returns the String under path decoded as standard base64. If path
+// is malformed returns ErrInvalidPath, if the element is not found returns
+// ErrNotFound and if it is not a String returns ErrTypeMissmatch. If the
+// String is not valid base64 returns the decoding error.
+func (j *JSON) GetBytes(path string) ([]byte, error) {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return nil, err
+	}
+	str, ok := ifc.(string)
+	if !ok {
+		return nil, ErrTypeMissmatch
+	}
+	return base64.StdEncoding.DecodeString(str)
+}
+
+// Interface returns the value under path as decoded by the json package
+// without any conversion: a bool, float64, string, []interface{},
+// map[string]interface{} or nil for a null. Objects and Arrays returned are
+// shared with the JSON, not copied. If path is malformed returns
+// ErrInvalidPath. If the element does not exist returns ErrNotFound.
+func (j *JSON) Interface(path string) (interface{}, error) {
+	return j.get(path)
+}
+
+// GetInt64 returns the Number under path as an int64. If the Number is not a
+// whole number or overflows an int64 returns ErrTruncate. Integers larger
+// than 2^53 lose precision unless the JSON was created by
+// UnmarshalUseNumber. If the value is not a Number returns ErrInvalidOut.
+func (j *JSON) GetInt64(path string) (int64, error) {
+
+	var v int64
+	if err := j.Get(path, &v); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// GetDuration returns the value under path as a time.Duration. A String is
+// parsed by time.ParseDuration, i.e. "1h30m", returning the parse error if
+// it is malformed. A Number is taken as a count of nanoseconds; if it is not
+// a whole number returns ErrTruncate. If the value is neither returns
+// ErrTypeMissmatch.
+func (j *JSON) GetDuration(path string) (time.Duration, error) {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return 0, err
+	}
+	if v, ok := ifc.(string); ok {
+		return time.ParseDuration(v)
+	}
+	if v, ok := number(ifc); ok {
+		if v-float64(int64(v)) != 0 {
+			return 0, ErrTruncate
+		}
+		return time.Duration(v), nil
+	}
+	return 0, ErrTypeMissmatch
+}
+
+// GetStringSlice returns the Array under path as a slice of strings. If the
+// element under path is not an Array or any of its elements is not a String
+// returns ErrTypeMissmatch.
+func (j *JSON) GetStringSlice(path string) ([]string, error) {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return nil, err
+	}
+	slc, ok := ifc.([]interface{})
+	if !ok {
+		return nil, ErrTypeMissmatch
+	}
+	result := make([]string, len(slc))
+	for i, v := range slc {
+		if result[i], ok = v.(string); !ok {
+			return nil, ErrTypeMissmatch
+		}
+	}
+	return result, nil
+}
+
+// GetIntSlice returns the Array under path as a slice of ints. If the element
+// under path is not an Array or any of its elements is not a Number returns
+// ErrTypeMissmatch. If any element would be truncated or overflow an int
+// returns ErrTruncate.
+func (j *JSON) GetIntSlice(path string) ([]int, error) {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return nil, err
+	}
+	slc, ok := ifc.([]interface{})
+	if !ok {
+		return nil, ErrTypeMissmatch
+	}
+	result := make([]int, len(slc))
+	for i, v := range slc {
+		f, ok := number(v)
+		if !ok {
+			return nil, ErrTypeMissmatch
+		}
+		if f-float64(int(f)) != 0 {
+			return nil, ErrTruncate
+		}
+		result[i] = int(f)
+	}
+	return result, nil
+}
+
+// GetWithTag is like Get but matches Object keys to struct fields by names
+// given in struct tags named tag instead of "json". If tag is empty "json" is
+// used.
+func (j *JSON) GetWithTag(path, tag string, out interface{}) error {
+	return (&JSON{intf: j.intf, tag: tag}).Get(path, out)
+}
+
+// Set sets a JSON element value by path. If path is malformed returns
+// ErrInvalidPath. Set forces the full path of an element and the element
+// itself discarding any overwritten entries without notice: missing or null
+// elements on the path are created as Objects, or as Arrays padded with
+// nulls up to the index if followed by an Array index. Existing Arrays are
+// not extended; if path addresses an index out of range of an existing Array
+// returns ErrOutOfRange. If path descends through an element that is not an
+// Object or Array returns ErrNotFound.
+//
+// Empty brackets in path append a new element to the Array, creating the
+// Array if it is missing, i.e. "a.tags[]" appends to "tags" and
+// "items[].name" appends a new Object with a "name" property to "items".
+// Empty brackets are valid only in paths given to Set.
+// On success function returns nil.
+func (j *JSON) Set(path string, in interface{}) error {
+
+	p, err := parse(path)
+	if err != nil {
+		return err
+	}
+	return j.SetPath(p, in)
+}
+
+// SetPath is like Set but takes a Path parsed by ParsePath.
+func (j *JSON) SetPath(path Path, in interface{}) error {
+
+	inv := reflect.ValueOf(in)
+	if !inv.IsValid() {
+		return ErrInvalidIn
+	}
+
+	ifc, err := normalize(in)
+	if err != nil {
+		return err
+	}
+
+	return j.putDeep(path, ifc)
+}
+
+// SetIfAbsent is like Set but sets in only if the element under path does not
+// exist, creating missing elements on path as Set does. An element with a
+// null value exists. Returns true if in was set and false if the element
+// already existed or an error occured.
+func (j *JSON) SetIfAbsent(path string, in interface{}) (bool, error) {
+
+	p, err := parse(path)
+	if err != nil {
+		return false, err
+	}
+	if _, err := j.getPath(p); err == nil {
+		return false, nil
+	} else if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrOutOfRange) {
+		return false, err
+	}
+	if err := j.SetPath(p, in); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetFirst reads the element under the first of paths that exists into out
+// as Get would, i.e. to fall back from a key to its deprecated name. Paths
+// which resolve to no element, because of ErrNotFound or ErrOutOfRange, are
+// skipped while any other error is returned. If none of paths exist returns
+// ErrNotFound.
+func (j *JSON) GetFirst(out interface{}, paths ...string) error {
+
+	for _, path := range paths {
+		err := j.Get(path, out)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrOutOfRange) {
+			continue
+		}
+		return err
+	}
+	return ErrNotFound
+}
+
+// GetOrSet reads the element under path into out as Get would. If the
+// element does not exist def is first set under path as by SetIfAbsent and
+// then read into out.
+func (j *JSON) GetOrSet(path string, def interface{}, out interface{}) error {
+
+	if _, err := j.SetIfAbsent(path, def); err != nil {
+		return err
+	}
+	return j.Get(path, out)
+}
+
+// SetOmitEmpty is like Set but if in is an empty value it deletes the element
+// under path instead, like the "omitempty" option of the json package omits
+// empty fields. Empty values are nil, false, 0, empty strings, nil pointers
+// and interfaces and empty arrays, slices and maps. Deleting an element that
+// does not exist is not an error.
+func (j *JSON) SetOmitEmpty(path string, in interface{}) error {
+
+	if !isEmpty(reflect.ValueOf(in)) {
+		return j.Set(path, in)
+	}
+	p, err := parse(path)
+	if err != nil {
+		return err
+	}
+	if err := j.removePath(p); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// isEmpty returns true if v is an empty value as defined by the json package.
+func isEmpty(v reflect.Value) bool {
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// SetMany sets values of updates under their paths as Set would, in order of
+// their paths sorted as strings. Updates are applied to a copy of the JSON
+// which replaces the JSON only if all updates succeed, so no update is
+// applied if any fails. The error of the first failed update is returned as
+// a *PathError with Path set to the path of the update; errors that are not
+// already a *PathError are wrapped in one whose Element is the whole path.
+func (j *JSON) SetMany(updates map[string]interface{}) error {
+
+	w := &JSON{intf: clone(j.intf)}
+	for _, path := range sortedKeys(updates) {
+		err := w.Set(path, updates[path])
+		if err == nil {
+			continue
+		}
+		if perr, ok := err.(*PathError); ok {
+			return perr
+		}
+		return &PathError{Path: path, Element: path, Err: err}
+	}
+	j.intf = w.intf
+
+	return nil
+}
+
+// Append appends in to the Array under path. If path is malformed returns
+// ErrInvalidPath. If the element under path is not an Array returns
+// ErrTypeMissmatch. On success function returns nil.
+func (j *JSON) Append(path string, in interface{}) error {
+
+	if !reflect.ValueOf(in).IsValid() {
+		return ErrInvalidIn
+	}
+
+	ifc, err := normalize(in)
+	if err != nil {
+		return err
+	}
+	slc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	sv, ok := slc.([]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+
+	return j.insert(path, len(sv), ifc)
+}
+
+// Insert inserts in into the Array under path at index, shifting elements
+// at and after index right. An index equal to the Array length appends in.
+// A negative index counts from the end of the Array, i.e. -1 inserts in
+// before the last element. If index is out of range returns ErrOutOfRange.
+// If the element under path is not an Array returns ErrTypeMissmatch.
+func (j *JSON) Insert(path string, index int, in interface{}) error {
+
+	if !reflect.ValueOf(in).IsValid() {
+		return ErrInvalidIn
+	}
+
+	ifc, err := normalize(in)
+	if err != nil {
+		return err
+	}
+	slc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	sv, ok := slc.([]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+
+	return j.insert(path, fromEnd(index, len(sv)), ifc)
+}
+
+// RemoveAt removes the element at index from the Array under path, shifting
+// elements after it left. A negative index counts from the end of the
+// Array, i.e. -1 removes the last element. If index is out of range returns
+// ErrOutOfRange. If the element under path is not an Array returns
+// ErrTypeMissmatch.
+func (j *JSON) RemoveAt(path string, index int) error {
+
+	_, err := j.take(path, index)
+	return err
+}
+
+// Splice removes deleteCount elements of the Array under path starting at
+// index start and inserts items in their place, like Array.prototype.splice
+// in JavaScript. A negative start counts from the end of the Array. A
+// deleteCount larger than the number of elements from start is reduced to
+// it and a negative one is taken as 0. If start is out of range returns
+// ErrOutOfRange, a start equal to the Array length appends items. If the
+// element under path is not an Array returns ErrTypeMissmatch. If any of
+// items is nil returns ErrInvalidIn.
+func (j *JSON) Splice(path string, start, deleteCount int, items ...interface{}) error {
+
+	ins := make([]interface{}, len(items))
+	for i, item := range items {
+		if !reflect.ValueOf(item).IsValid() {
+			return ErrInvalidIn
+		}
+		v, err := normalize(item)
+		if err != nil {
+			return err
+		}
+		ins[i] = v
+	}
+	slc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	sv, ok := slc.([]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+	start = fromEnd(start, len(sv))
+	if start < 0 || start > len(sv) {
+		return ErrOutOfRange
+	}
+	if deleteCount < 0 {
+		deleteCount = 0
+	}
+	if deleteCount > len(sv)-start {
+		deleteCount = len(sv) - start
+	}
+
+	result := make([]interface{}, 0, len(sv)-deleteCount+len(ins))
+	result = append(result, sv[:start]...)
+	result = append(result, ins...)
+	result = append(result, sv[start+deleteCount:]...)
+
+	return j.put(path, result)
+}
+
+// Swap exchanges the elements at indexes a and b of the Array under path.
+// Negative indexes count from the end of the Array. If either index is out
+// of range returns ErrOutOfRange. If the element under path is not an Array
+// returns ErrTypeMissmatch.
+func (j *JSON) Swap(path string, a, b int) error {
+
+	slc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	sv, ok := slc.([]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+	a, b = fromEnd(a, len(sv)), fromEnd(b, len(sv))
+	if a < 0 || a >= len(sv) || b < 0 || b >= len(sv) {
+		return ErrOutOfRange
+	}
+	sv[a], sv[b] = sv[b], sv[a]
+
+	return nil
+}
+
+// SortBy sorts the Array under path in place by values of property field of
+// its Object elements, in ascending order if ascending is true, descending
+// otherwise. Numbers are compared numerically and Strings lexically.
+// Elements whose field is a Number come first, followed by those whose field
+// is a String, followed by all other elements, including non-Objects and
+// Objects without the field, in their original order. If the element under
+// path is not an Array returns ErrTypeMissmatch.
+func (j *JSON) SortBy(path, field string, ascending bool) error {
+
+	slc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	sv, ok := slc.([]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+
+	// rank returns the group of element v and its field value.
+	rank := func(v interface{}) (int, interface{}) {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return 2, nil
+		}
+		if f, ok := number(m[field]); ok {
+			return 0, f
+		}
+		if s, ok := m[field].(string); ok {
+			return 1, s
+		}
+		return 2, nil
+	}
+	sort.SliceStable(sv, func(a, b int) bool {
+		ra, va := rank(sv[a])
+		rb, vb := rank(sv[b])
+		if ra != rb || ra == 2 {
+			return ra < rb
+		}
+		if ra == 0 {
+			if ascending {
+				return va.(float64) < vb.(float64)
+			}
+			return va.(float64) > vb.(float64)
+		}
+		if ascending {
+			return va.(string) < vb.(string)
+		}
+		return va.(string) > vb.(string)
+	})
+
+	return nil
+}
+
+// Push appends in to the Array under path. It is equivalent to Append.
+func (j *JSON) Push(path string, in interface{}) error {
+	return j.Append(path, in)
+}
+
+// Pop removes the last element of the Array under path and returns it. If
+// the Array is empty returns ErrOutOfRange. If the element under path is not
+// an Array returns ErrTypeMissmatch.
+func (j *JSON) Pop(path string) (*JSON, error) {
+	return j.take(path, -1)
+}
+
+// Shift removes the first element of the Array under path and returns it,
+// shifting remaining elements left. If the Array is empty returns
+// ErrOutOfRange. If the element under path is not an Array returns
+// ErrTypeMissmatch.
+func (j *JSON) Shift(path string) (*JSON, error) {
+	return j.take(path, 0)
+}
+
+// Unshift inserts in as the first element of the Array under path, shifting
+// existing elements right. It is equivalent to Insert at index 0.
+func (j *JSON) Unshift(path string, in interface{}) error {
+	return j.Insert(path, 0, in)
+}
+
+// take removes the element at index from the Array under path and returns
+// it. A negative index counts from the end of the Array.
+func (j *JSON) take(path string, index int) (*JSON, error) {
+
+	slc, err := j.get(path)
+	if err != nil {
+		return nil, err
+	}
+	sv, ok := slc.([]interface{})
+	if !ok {
+		return nil, ErrTypeMissmatch
+	}
+	index = fromEnd(index, len(sv))
+	if index < 0 || index >= len(sv) {
+		return nil, ErrOutOfRange
+	}
+	v := sv[index]
+	if err := j.put(path, append(sv[:index:index], sv[index+1:]...)); err != nil {
+		return nil, err
+	}
+
+	return &JSON{intf: v}, nil
+}
+
+// Clear replaces the Object or Array under path with an empty one of the same
+// kind, keeping the element itself in its parent. If the element is neither
+// an Object nor an Array returns ErrTypeMissmatch.
+func (j *JSON) Clear(path string) error {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	switch ifc.(type) {
+	case map[string]interface{}:
+		return j.put(path, map[string]interface{}{})
+	case []interface{}:
+		return j.put(path, []interface{}{})
+	}
+	return ErrTypeMissmatch
+}
+
+// DeleteIf removes each element of the Array under path for which pred
+// returns true, keeping the order of remaining elements. Pred is called for
+// each element in order with its index in the original Array and a JSON
+// wrapping it. If the element under path is not an Array returns
+// ErrTypeMissmatch.
+func (j *JSON) DeleteIf(path string, pred func(index int, item *JSON) bool) error {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	slc, ok := ifc.([]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+	kept := make([]interface{}, 0, len(slc))
+	for i, v := range slc {
+		if !pred(i, &JSON{intf: v}) {
+			kept = append(kept, v)
+		}
+	}
+
+	return j.put(path, kept)
+}
+
+// fromEnd returns index unchanged if not negative, otherwise index counted
+// from the end of an Array of length n.
+func fromEnd(index, n int) int {
+
+	if index < 0 {
+		return n + index
+	}
+	return index
+}
+
+// Copy sets a deep copy of the element under fromPath to toPath as Set would,
+// creating elements on toPath as required. Copy and the source do not share
+// any Objects or Arrays. If fromPath specifies a non-existent element returns
+// ErrNotFound.
+func (j *JSON) Copy(fromPath, toPath string) error {
+
+	v, err := j.get(fromPath)
+	if err != nil {
+		return err
+	}
+	p, err := parse(toPath)
+	if err != nil {
+		return err
+	}
+	return j.putDeep(p, clone(v))
+}
+
+// Move moves the element under fromPath to toPath. The element is first
+// deleted from fromPath as by Delete, then set to toPath as by Set, so
+// indexes in toPath of the Array fromPath was deleted from are resolved after
+// the deletion. If fromPath specifies a non-existent element returns
+// ErrNotFound. If toPath addresses a descendant of the element under
+// fromPath returns ErrInvalidPath. The move is done on a copy of the JSON
+// which replaces the JSON only if it succeeded, so if setting toPath fails
+// the JSON is left unchanged.
+func (j *JSON) Move(fromPath, toPath string) error {
+
+	fp, err := parse(fromPath)
+	if err != nil {
+		return err
+	}
+	tp, err := parse(toPath)
+	if err != nil {
+		return err
+	}
+	if isPrefix(fp, tp) {
+		if len(fp) == len(tp) {
+			return nil
+		}
+		return ErrInvalidPath
+	}
+
+	w := &JSON{intf: clone(j.intf)}
+	v, err := w.getPath(fp)
+	if err != nil {
+		return err
+	}
+	if err := w.removePath(fp); err != nil {
+		return err
+	}
+	if err := w.putDeep(tp, v); err != nil {
+		return err
+	}
+	j.intf = w.intf
+
+	return nil
+}
+
+// isPrefix returns true if path starts with prefix.
+func isPrefix(prefix, path Path) bool {
+
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Delete deletes an element by path from its parent Object or Array. Array
+// elements following a deleted element are shifted left. If path is
+// malformed returns ErrInvalidPath. If path specifies a non-existent element
+// returns ErrNotFound. On success function returns nil.
+//
+// Path may contain "[*]" wildcards in which case all matched elements are
+// deleted, i.e. "items[*].secret" deletes "secret" from each element of
+// "items". Elements that do not contain the rest of the path after a
+// wildcard are skipped. Deletions are applied to a copy of the JSON which
+// replaces the JSON only if all of them succeeded.
+func (j *JSON) Delete(path string) error {
+
+	p, err := parse(path)
+	if err != nil {
+		return err
+	}
+	if !p.HasWildcard() {
+		return j.removePath(p)
+	}
+
+	w := &JSON{intf: clone(j.intf)}
+	paths, err := w.expand(p)
+	if err != nil {
+		return err
+	}
+	for i := len(paths) - 1; i >= 0; i-- {
+		err := w.removePath(paths[i])
+		if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrOutOfRange) {
+			return err
+		}
+	}
+	j.intf = w.intf
+
+	return nil
+}
+
+// Rename renames the Object property under path to newKey keeping its value.
+// A property already named newKey is overwritten. If path addresses the root
+// element returns ErrInvalidPath, if it addresses an Array element returns
+// ErrTypeMissmatch and if the property does not exist returns ErrNotFound.
+func (j *JSON) Rename(path, newKey string) error {
+
+	p, err := parse(path)
+	if err != nil {
+		return err
+	}
+	key, tgt, err := j.find(p, true)
+	if err != nil {
+		return err
+	}
+	m, ok := tgt.(map[string]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+	v, ok := m[key.String()]
+	if !ok {
+		return newPathError(p, len(p)-1, ErrNotFound)
+	}
+	delete(m, key.String())
+	m[newKey] = v
+
+	return nil
+}
+
+// Parent returns the path of the Object or Array containing the element
+// under path, in the form returned by Path.String. If path is malformed or
+// addresses the root element returns ErrInvalidPath. If the element does not
+// exist returns ErrNotFound.
+func (j *JSON) Parent(path string) (string, error) {
+
+	p, err := parse(path)
+	if err != nil {
+		return "", err
+	}
+	if len(p) == 0 {
+		return "", ErrInvalidPath
+	}
+	if _, err := j.getPath(p); err != nil {
+		return "", err
+	}
+	return p[:len(p)-1].String(), nil
+}
+
+// DecodeUnion decodes a tagged union Object under path. It reads the String
+// value of the discriminator property of the Object and assigns the whole
+// Object to the target registered for that value in targets as Get would.
+// Targets must be pointers to variables. Discriminator is a property name,
+// not a path, and may contain any character. If the discriminator value has
+// no registered target returns ErrNoTarget.
+func (j *JSON) DecodeUnion(path, discriminator string, targets map[string]interface{}) error {
+
+	p, err := parse(path)
+	if err != nil {
+		return err
+	}
+	dp := append(append(Path{}, p...), Segment{Key: discriminator})
+	var name string
+	if err := j.GetPath(dp, &name); err != nil {
+		return err
+	}
+	out, ok := targets[name]
+	if !ok {
+		return ErrNoTarget
+	}
+
+	return j.Get(path, out)
+}
+
+// FlattenArray flattens nested Arrays of the Array under path up to depth
+// levels, replacing it with the flattened Array. A negative depth flattens
+// all levels. If the element under path is not an Array returns
+// ErrTypeMissmatch.
+func (j *JSON) FlattenArray(path string, depth int) error {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	slc, ok := ifc.([]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+
+	return j.put(path, flatten(slc, depth))
+}
+
+// flatten returns a new Array with elements of nested Arrays in slc up to
+// depth levels merged into it. A negative depth flattens all levels.
+func flatten(slc []interface{}, depth int) []interface{} {
+
+	result := make([]interface{}, 0, len(slc))
+	for _, v := range slc {
+		sub, ok := v.([]interface{})
+		if !ok || depth == 0 {
+			result = append(result, v)
+			continue
+		}
+		result = append(result, flatten(sub, depth-1)...)
+	}
+	return result
+}
+
+// Equal returns true if the JSON is semantically equal to other. Objects
+// are equal if they have the same keys with equal values regardless of key
+// order, Arrays if they have the same length and equal elements in order.
+func (j *JSON) Equal(other *JSON) bool {
+
+	if j == nil || other == nil {
+		return j == other
+	}
+	return equal(j.intf, other.intf)
+}
+
+// equal recursively compares two values in the form produced by normalize.
+func equal(a, b interface{}) bool {
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, val := range av {
+			other, ok := bv[key]
+			if !ok || !equal(val, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if af, ok := number(a); ok {
+		bf, ok := number(b)
+		if !ok {
+			return false
+		}
+		ai, aok := integer(a)
+		bi, bok := integer(b)
+		if aok && bok {
+			return ai == bi
+		}
+		return af == bf
+	}
+	return a == b
+}
+
+// integer returns the value of Number v as an int64 and true if v is an
+// integer an int64 holds exactly, so that integers stored as json.Number by
+// UnmarshalUseNumber can be compared without losing precision.
+func integer(v interface{}) (int64, bool) {
+
+	switch t := v.(type) {
+	case float64:
+		if t == math.Trunc(t) && t >= -(1<<63) && t < 1<<63 {
+			return int64(t), true
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(string(t), 10, 64); err == nil {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// number returns the value of v as a float64 and true if v is a Number,
+// either a float64 or a json.Number as stored by UnmarshalUseNumber.
+func number(v interface{}) (float64, bool) {
+
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// Test returns true if the value under path is deeply equal to expected as
+// it would be stored by Set, like the "test" operation of ApplyPatch. If
+// path is malformed returns ErrInvalidPath. If the element does not exist
+// returns ErrNotFound. If expected cannot be encoded returns the encoding
+// error.
+func (j *JSON) Test(path string, expected interface{}) (bool, error) {
+
+	v, err := j.get(path)
+	if err != nil {
+		return false, err
+	}
+	want, err := normalizeUseNumber(expected)
+	if err != nil {
+		return false, err
+	}
+	return equal(v, want), nil
+}
+
+// Require returns nil if the element under path exists and is of kind, one
+// of "object", "array", "string", "number", "bool" or "null". Calls to
+// Require can be chained to check the shape of a document. If the element
+// is of another kind returns a *KindError. If path is malformed returns
+// ErrInvalidPath, if the element does not exist returns ErrNotFound and if
+// kind is not one of the above returns ErrInvalidIn.
+func (j *JSON) Require(path, kind string) error {
+
+	switch kind {
+	case "object", "array", "string", "number", "bool", "null":
+	default:
+		return ErrInvalidIn
+	}
+	v, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	if actual := kindOf(v); actual != kind {
+		return &KindError{Path: path, Expected: kind, Actual: actual}
+	}
+	return nil
+}
+
+// RootKind returns the kind of the root element of the JSON as named by
+// Require: "object", "array", "string", "number", "bool" or "null".
+func (j *JSON) RootKind() string {
+	return kindOf(j.intf)
+}
+
+// kindOf returns the name of the kind of value v as used by Require.
+func kindOf(v interface{}) string {
+
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "bool"
+	case nil:
+		return "null"
+	}
+	return "unknown"
+}
+
+// IsNull returns true if the element under path exists and is null. If path
+// is malformed returns ErrInvalidPath. If path specifies a non-existent
+// element returns false and ErrNotFound, which distinguishes an absent
+// element from a present null one.
+func (j *JSON) IsNull(path string) (bool, error) {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return false, err
+	}
+	return ifc == nil, nil
+}
+
+// Len returns the length of the Array specified by path. If path is malformed
+// returns ErrInvalidPath. If Array is not found returns ErrNotFound. Returns
+// the Array length on success or -1 and an error otherwise.
+func (j *JSON) Len(path string) (int, error) {
+
+	slc, err := j.get(path)
+	if err != nil {
+		return -1, err
+	}
+	slcv, ok := slc.([]interface{})
+	if !ok {
+		return -1, ErrInvalidPath
+	}
+	return len(slcv), nil
+}
+
+// Count returns the number of direct children of the element under path: the
+// number of elements of an Array or properties of an Object. Elements of any
+// other kind, including null, have no children and Count returns 0 for them.
+// If path is malformed returns ErrInvalidPath. If the element is not found
+// returns ErrNotFound. Returns -1 and an error on failure.
+func (j *JSON) Count(path string) (int, error) {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return -1, err
+	}
+	switch v := ifc.(type) {
+	case map[string]interface{}:
+		return len(v), nil
+	case []interface{}:
+		return len(v), nil
+	}
+	return 0, nil
+}
+
+// Range calls fn for each element of the Array under path in order, passing
+// the element index and a JSON wrapping the element. Object and Array
+// elements are shared with the JSON, so changes made to them through item
+// are reflected in the JSON. If fn returns an error Range stops and returns
+// it. If the element under path is not an Array returns ErrTypeMissmatch.
+func (j *JSON) Range(path string, fn func(index int, item *JSON) error) error {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	slc, ok := ifc.([]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+	for i, v := range slc {
+		if err := fn(i, &JSON{intf: v}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Select returns JSONs wrapping each Object element of the Array under path
+// whose property field equals value. Value is compared after being encoded
+// and decoded as by Set. Elements that are not Objects or have no property
+// field are skipped. Returned elements are shared with the JSON like items
+// passed by Range. If the element under path is not an Array returns
+// ErrTypeMissmatch.
+func (j *JSON) Select(path, field string, value interface{}) ([]*JSON, error) {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return nil, err
+	}
+	slc, ok := ifc.([]interface{})
+	if !ok {
+		return nil, ErrTypeMissmatch
+	}
+	want, err := normalizeUseNumber(value)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*JSON{}
+	for _, v := range slc {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if fv, ok := m[field]; ok && equal(fv, want) {
+			result = append(result, &JSON{intf: m})
+		}
+	}
+	return result, nil
+}
+
+// ForEach calls fn for each property of the Object under path in key order,
+// passing the key and a JSON wrapping the value. Object and Array values are
+// shared with the JSON, so changes made to them through value are reflected
+// in the JSON. If fn returns an error ForEach stops and returns it. If the
+// element under path is not an Object returns ErrTypeMissmatch.
+func (j *JSON) ForEach(path string, fn func(key string, value *JSON) error) error {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	m, ok := ifc.(map[string]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+	for _, key := range sortedKeys(m) {
+		if err := fn(key, &JSON{intf: m[key]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns keys of m sorted in increasing order.
+func sortedKeys(m map[string]interface{}) []string {
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Export exports the JSON in its' current state as a slice of bytes.
+func (j *JSON) Export(indent string) ([]byte, error) {
+	return j.ExportOptions(ExportOptions{Indent: indent, EscapeHTML: true})
+}
+
+// StreamArray writes the Array under path to w as a compact JSON Array whose
+// elements are values returned by fn for each element of the Array, in
+// order. Each value is encoded and written to w before fn is called for the
+// next element so the whole output is never held in memory. Elements are
+// passed to fn as by Range. If fn returns an error or writing to w fails
+// StreamArray stops and returns the error, leaving the output incomplete.
+// If the element under path is not an Array returns ErrTypeMissmatch.
+func (j *JSON) StreamArray(path string, w io.Writer, fn func(item *JSON) (interface{}, error)) error {
+
+	ifc, err := j.get(path)
+	if err != nil {
+		return err
+	}
+	slc, ok := ifc.([]interface{})
+	if !ok {
+		return ErrTypeMissmatch
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i, v := range slc {
+		out, err := fn(&JSON{intf: v})
+		if err != nil {
+			return err
+		}
+		b, err := json.Marshal(out)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "]")
+	return err
+}
+
+// ExportRaw is like Export but does not escape "<", ">" and "&" in Strings,
+// producing cleaner output for contexts other than HTML.
+func (j *JSON) ExportRaw(indent string) ([]byte, error) {
+	return j.ExportOptions(ExportOptions{Indent: indent})
+}
+
+// ExportOptions specifies options for ExportOptions.
+type ExportOptions struct {
+	// Indent is the string each nesting level is indented with. If empty the
+	// output is compact.
+	Indent string
+	// EscapeHTML specifies if "<", ">" and "&" in Strings are escaped as
+	// \u003c, \u003e and \u0026 so that the output is safe to embed in HTML.
+	EscapeHTML bool
+}
+
+// ExportOptions exports the JSON in its current state as a slice of bytes
+// formatted according to opts. Object keys are always sorted.
+func (j *JSON) ExportOptions(opts ExportOptions) ([]byte, error) {
+
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(opts.EscapeHTML)
+	enc.SetIndent("", opts.Indent)
+	if err := enc.Encode(j.intf); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Canonical returns the JSON in its current state in a canonical compact form
+// so that semantically equal documents produce identical output. Object keys
+// are sorted, Numbers are formatted in their shortest representation so that
+// i.e. 1, 1.0 and 1e0 all produce 1 and Strings are escaped minimally,
+// without escaping HTML characters. Integers stored as json.Number by
+// UnmarshalUseNumber which an int64 holds are written as they are, keeping
+// their precision.
+func (j *JSON) Canonical() ([]byte, error) {
+	return (&JSON{intf: canonicalNumbers(j.intf)}).ExportOptions(ExportOptions{})
+}
+
+// canonicalNumbers returns a copy of v with json.Number values converted to
+// float64, except integers an int64 holds exactly which are kept verbatim so
+// that they do not lose precision.
+func canonicalNumbers(v interface{}) interface{} {
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for key, val := range t {
+			m[key] = canonicalNumbers(val)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, val := range t {
+			s[i] = canonicalNumbers(val)
+		}
+		return s
+	case json.Number:
+		if _, ok := integer(t); ok {
+			return t
+		}
+		if f, ok := number(t); ok {
+			return f
+		}
+	}
+	return v
+}
+
+// String implements the fmt.Stringer interface. It returns the JSON in its
+// current state in compact form, "<invalid json>" if it cannot be exported
+// or "<nil>" if j is nil.
+func (j *JSON) String() string {
+
+	if j == nil {
+		return "<nil>"
+	}
+	b, err := j.Export("")
+	if err != nil {
+		return "<invalid json>"
+	}
+	return string(b)
+}
+
+// expand expands wildcard segments of path into indexes of all elements of
+// the Arrays they address and returns the resulting Paths. A path without
+// wildcards is returned as the only result. If the Array addressed by the
+// first wildcard does not exist returns an error, nested wildcards
+// addressing non-existent Arrays expand to nothing.
+func (j *JSON) expand(path Path) ([]Path, error) {
+
+	w := -1
+	for i, seg := range path {
+		if seg.Wildcard {
+			w = i
+			break
+		}
+	}
+	if w < 0 {
+		return []Path{path}, nil
+	}
+
+	ifc, err := j.getPath(path[:w])
+	if err != nil {
+		return nil, err
+	}
+	slc, ok := ifc.([]interface{})
+	if !ok {
+		return nil, newPathError(path, w, ErrNotFound)
+	}
+
+	result := []Path{}
+	for i := range slc {
+		p := append(Path{}, path...)
+		p[w] = Segment{Index: i, Array: true}
+		paths, err := j.expand(p)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrOutOfRange) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, paths...)
+	}
+	return result, nil
+}
+
+// match returns values of all existing elements matched by path whose
+// wildcards are expanded as by expand.
+func (j *JSON) match(path Path) ([]interface{}, error) {
+
+	paths, err := j.expand(path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, 0, len(paths))
+	for _, p := range paths {
+		ifc, err := j.getPath(p)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrOutOfRange) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ifc)
+	}
+	return result, nil
+}
+
+// Stats holds statistics of Number values matched by a wildcard pattern.
+type Stats struct {
+	Count  int     // Count is the number of matched Numbers.
+	Sum    float64 // Sum is the sum of matched Numbers.
+	Min    float64 // Min is the smallest matched Number.
+	Max    float64 // Max is the largest matched Number.
+	Mean   float64 // Mean is the arithmetic mean of matched Numbers.
+	StdDev float64 // StdDev is the population standard deviation.
+}
+
+// Stats computes statistics over all Number values matched by pattern in a
+// single pass. Pattern is a path which may contain "[*]" wildcards in place
+// of Array indexes, i.e. "planets[*].moons". Matches that are not Numbers
+// or elements that do not contain the rest of the path are skipped. If no
+// Numbers were matched returns ErrNotFound.
+func (j *JSON) Stats(pattern string) (Stats, error) {
+
+	st := Stats{}
+	p, err := parse(pattern)
+	if err != nil {
+		return st, err
+	}
+	values, err := j.match(p)
+	if err != nil {
+		return st, err
+	}
+
+	m2 := 0.0
+	for _, ifc := range values {
+		v, ok := number(ifc)
+		if !ok {
+			continue
+		}
+		if st.Count == 0 || v < st.Min {
+			st.Min = v
+		}
+		if st.Count == 0 || v > st.Max {
+			st.Max = v
+		}
+		st.Count++
+		st.Sum += v
+		delta := v - st.Mean
+		st.Mean += delta / float64(st.Count)
+		m2 += delta * (v - st.Mean)
+	}
+	if st.Count == 0 {
+		return st, ErrNotFound
+	}
+	st.StdDev = math.Sqrt(m2 / float64(st.Count))
+
+	return st, nil
+}
+
+// FindKeys returns paths of all Object properties named name at any depth of
+// the JSON, in depth first order with Object properties visited in key order.
+func (j *JSON) FindKeys(name string) ([]string, error) {
+
+	result := []string{}
+	err := walk(Path{}, j.intf, func(path Path, v interface{}) error {
+		if len(path) > 0 && path[len(path)-1].Key == name && !path[len(path)-1].Array {
+			result = append(result, path.String())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Redact replaces values of elements under paths with the String "***". It
+// is equivalent to RedactWith using "***" as the placeholder.
+func (j *JSON) Redact(paths ...string) error {
+	return j.RedactWith("***", paths...)
+}
+
+// RedactWith replaces values of elements under paths with the String
+// placeholder, i.e. to hide secrets before logging the JSON. Paths may
+// contain "[*]" wildcards, i.e. "[*].password" redacts the "password" of each
+// element of the root Array. Paths addressing elements that do not exist are
+// skipped. If any of paths is malformed returns ErrInvalidPath and leaves
+// the JSON unmodified.
+func (j *JSON) RedactWith(placeholder string, paths ...string) error {
+
+	parsed := make([]Path, 0, len(paths))
+	for _, path := range paths {
+		p, err := parse(path)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, p)
+	}
+
+	for _, path := range parsed {
+		expanded, err := j.expand(path)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrOutOfRange) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, p := range expanded {
+			if _, err := j.getPath(p); err != nil {
+				continue
+			}
+			if err := j.putPath(p, placeholder); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Visitor receives elements of a JSON visited by WalkTyped. Each method is
+// called with the path of the element and its value, if any. If a method
+// returns an error the walk stops and WalkTyped returns it.
+type Visitor interface {
+	// OnObject is called for an Object before its properties are visited.
+	OnObject(path string) error
+	// OnArray is called for an Array before its elements are visited.
+	OnArray(path string) error
+	// OnString is called for a String.
+	OnString(path string, v string) error
+	// OnNumber is called for a Number.
+	OnNumber(path string, v float64) error
+	// OnBool is called for a Boolean.
+	OnBool(path string, v bool) error
+	// OnNull is called for a null.
+	OnNull(path string) error
+}
+
+// WalkTyped visits all elements of the JSON, starting with the root element,
+// in depth first order with Object properties visited in key order, calling
+// the method of v corresponding to the kind of each element.
+func (j *JSON) WalkTyped(v Visitor) error {
+
+	return walk(Path{}, j.intf, func(path Path, ifc interface{}) error {
+		switch t := ifc.(type) {
+		case map[string]interface{}:
+			return v.OnObject(path.String())
+		case []interface{}:
+			return v.OnArray(path.String())
+		case string:
+			return v.OnString(path.String(), t)
+		case float64, json.Number:
+			f, ok := number(t)
+			if !ok {
+				return ErrTypeMissmatch
+			}
+			return v.OnNumber(path.String(), f)
+		case bool:
+			return v.OnBool(path.String(), t)
+		case nil:
+			return v.OnNull(path.String())
+		}
+		return ErrTypeMissmatch
+	})
+}
+
+// walk calls fn for v under path and then recursively for each of its
+// descendants in depth first order, visiting Object properties in key order.
+// If fn returns an error walk stops and returns it. Path passed to fn is
+// reused between calls and must not be retained by fn.
+func walk(path Path, v interface{}, fn func(path Path, v interface{}) error) error {
+
+	if err := fn(path, v); err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(t) {
+			if err := walk(append(path, Segment{Key: key}), t[key], fn); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, val := range t {
+			if err := walk(append(path, Segment{Index: i, Array: true}), val, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CountLeaves returns the number of values in the JSON which are not Objects
+// or Arrays, including nulls. Empty Objects and Arrays contain no leaves.
+func (j *JSON) CountLeaves() int {
+	return countLeaves(j.intf)
+}
+
+// countLeaves returns the number of leaves in v.
+func countLeaves(v interface{}) int {
+
+	n := 0
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, val := range t {
+			n += countLeaves(val)
+		}
+	case []interface{}:
+		for _, val := range t {
+			n += countLeaves(val)
+		}
+	default:
+		n = 1
+	}
+	return n
+}
+
+// Leaves returns values of all elements of the JSON which are not Objects or
+// Arrays, including nulls, in depth first order with Object properties
+// visited in key order, as by WalkTyped. The number of returned values
+// equals CountLeaves.
+func (j *JSON) Leaves() []interface{} {
+
+	result := []interface{}{}
+	walk(Path{}, j.intf, func(path Path, v interface{}) error {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil
+		}
+		result = append(result, v)
+		return nil
+	})
+	return result
+}
+
+// MaxDepth returns the deepest nesting level of Objects and Arrays in the
+// JSON. A root element that is not an Object or Array has depth 0, an Object
+// or Array of non-container values has depth 1 and each further level of
+// nesting adds 1.
+func (j *JSON) MaxDepth() int {
+	return maxDepth(j.intf)
+}
+
+// maxDepth returns the depth of v.
+func maxDepth(v interface{}) int {
+
+	max := 0
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, val := range t {
+			if d := maxDepth(val); d > max {
+				max = d
+			}
+		}
+	case []interface{}:
+		for _, val := range t {
+			if d := maxDepth(val); d > max {
+				max = d
+			}
+		}
+	default:
+		return 0
+	}
+	return max + 1
+}
+
+// Flatten returns a map of paths to values of all leaf elements of the JSON.
+// Leaf elements are Strings, Numbers, Booleans, nulls and empty Objects and
+// Arrays. Paths are in the canonical form accepted by Get, with keys that are
+// empty or contain dots, square brackets or double quotes written quoted, so
+// every value can be read back using its path.
+func (j *JSON) Flatten() map[string]interface{} {
+
+	result := make(map[string]interface{})
+	walk(Path{}, j.intf, func(path Path, v interface{}) error {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			if len(t) > 0 {
+				return nil
+			}
+		case []interface{}:
+			if len(t) > 0 {
+				return nil
+			}
+		}
+		result[path.String()] = v
+		return nil
+	})
+	return result
+}
+
+// Unflatten constructs a new JSON from a map of paths to values as returned
+// by Flatten. Objects and Arrays are created as required by paths; Arrays are
+// padded with nulls up to the highest index addressed. Values are converted
+// as by Set. If a path is malformed or contains a wildcard returns
+// ErrInvalidPath. If two paths conflict returns ErrConflict.
+func Unflatten(m map[string]interface{}) (*JSON, error) {
+
+	keys := sortedKeys(m)
+	j := &JSON{}
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		p, err := parse(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := normalize(m[key])
+		if err != nil {
+			return nil, err
+		}
+		if err := unflatten(&j.intf, p, 0, v, set); err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// unflatten stores v under path starting at segment i of path which
+// addresses node, creating missing Objects and Arrays. Set holds canonical
+// paths of elements already stored, so that stored nulls are told from
+// missing values. If node or any element on path is not a container or an
+// element under path already has a value returns ErrConflict.
+func unflatten(node *interface{}, path Path, i int, v interface{}, set map[string]bool) error {
+
+	if set[path[:i].String()] {
+		return ErrConflict
+	}
+	if i == len(path) {
+		if *node != nil {
+			return ErrConflict
+		}
+		*node = v
+		set[path.String()] = true
+		return nil
+	}
+
+	seg := path[i]
+	if seg.Wildcard || seg.Append {
+		return ErrInvalidPath
+	}
+	if *node == nil {
+		if seg.Array {
+			*node = []interface{}{}
+		} else {
+			*node = map[string]interface{}{}
+		}
+	}
+
+	if seg.Array {
+		slc, ok := (*node).([]interface{})
+		if !ok {
+			return ErrConflict
+		}
+		for len(slc) <= seg.Index {
+			slc = append(slc, nil)
+		}
+		*node = slc
+		return unflatten(&slc[seg.Index], path, i+1, v, set)
+	}
+
+	mv, ok := (*node).(map[string]interface{})
+	if !ok {
+		return ErrConflict
+	}
+	child := mv[seg.Key]
+	err := unflatten(&child, path, i+1, v, set)
+	mv[seg.Key] = child
+	return err
+}
+
+// Txn is a transaction on a JSON started by Begin.
+type Txn struct {
+	j        *JSON       // j is the JSON the transaction was started on.
+	snapshot interface{} // snapshot is a copy of j at the start.
+	done     bool        // done is true after Commit or Rollback.
+}
+
+// Begin starts a transaction on the JSON by taking a snapshot of its current
+// state. Changes made to the JSON afterwards, i.e. by Set or Delete, are kept
+// by calling Commit on the returned Txn or discarded by calling Rollback.
+func (j *JSON) Begin() *Txn {
+	return &Txn{j: j, snapshot: clone(j.intf)}
+}
+
+// Commit keeps the changes made to the JSON since Begin. Calling Commit or
+// Rollback after the transaction is finished does nothing.
+func (t *Txn) Commit() {
+	t.done = true
+	t.snapshot = nil
+}
+
+// Rollback restores the JSON to its state at Begin. Calling Commit or
+// Rollback after the transaction is finished does nothing.
+func (t *Txn) Rollback() {
+
+	if t.done {
+		return
 	}
-	return b, nil
+	t.j.intf = t.snapshot
+	t.done = true
+	t.snapshot = nil
 }
@@ -2,9 +2,17 @@ package jsonobj
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 // c constructs the string out of in parameters.
@@ -126,3 +134,3148 @@ func TestSet1(t *testing.T) {
 	p(string(out))
 
 }
+
+func TestStats(t *testing.T) {
+
+	const json = `{
+		"items" : [
+			{ "value": 2 },
+			{ "value": 4 },
+			{ "value": 4 },
+			{ "value": "four" },
+			{ "value": 4 },
+			{ "other": 1 },
+			{ "value": 5 },
+			{ "value": 5 },
+			{ "value": 7 },
+			{ "value": 9 }
+		]
+}`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestStats failed", err)
+	}
+	st, err := j.Stats("items[*].value")
+	if err != nil {
+		t.Fatal("TestStats.Stats failed", err)
+	}
+	if st.Count != 8 || st.Sum != 40 || st.Min != 2 || st.Max != 9 ||
+		st.Mean != 5 || st.StdDev != 2 {
+		t.Fatal("TestStats.Stats failed", st)
+	}
+	if _, err := j.Stats("items[*].none"); err != ErrNotFound {
+		t.Fatal("TestStats.Stats failed", err)
+	}
+}
+
+func TestDecodeUnion(t *testing.T) {
+
+	const json = `{
+		"shapes" : [
+			{ "type": "circle", "radius": 2 },
+			{ "type": "rect", "width": 3, "height": 4 },
+			{ "type": "star" }
+		]
+}`
+
+	type Circle struct {
+		Radius float64 `json:"radius"`
+	}
+	type Rect struct {
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	}
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestDecodeUnion failed", err)
+	}
+
+	circle, rect := Circle{}, Rect{}
+	targets := map[string]interface{}{"circle": &circle, "rect": &rect}
+	if err := j.DecodeUnion("shapes[0]", "type", targets); err != nil {
+		t.Fatal("TestDecodeUnion.DecodeUnion failed", err)
+	}
+	if err := j.DecodeUnion("shapes[1]", "type", targets); err != nil {
+		t.Fatal("TestDecodeUnion.DecodeUnion failed", err)
+	}
+	if circle.Radius != 2 || rect.Width != 3 || rect.Height != 4 {
+		t.Fatal("TestDecodeUnion.DecodeUnion failed", circle, rect)
+	}
+	if err := j.DecodeUnion("shapes[2]", "type", targets); err != ErrNoTarget {
+		t.Fatal("TestDecodeUnion.DecodeUnion failed", err)
+	}
+	if j, err = Unmarshal([]byte(`{ "s": { "kind.v": "circle", "radius": 5 } }`)); err != nil {
+		t.Fatal("TestDecodeUnion failed", err)
+	}
+	if err := j.DecodeUnion("s", "kind.v", targets); err != nil || circle.Radius != 5 {
+		t.Fatal("TestDecodeUnion.DecodeUnion failed", circle, err)
+	}
+}
+
+func TestFlattenArray(t *testing.T) {
+
+	const json = `{ "a": [[1,2],[3,[4,[5]]],6], "b": 1 }`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestFlattenArray failed", err)
+	}
+	if err := j.FlattenArray("a", 1); err != nil {
+		t.Fatal("TestFlattenArray.FlattenArray failed", err)
+	}
+	if out, _ := j.Export(""); string(out) != `{"a":[1,2,3,[4,[5]],6],"b":1}` {
+		t.Fatal("TestFlattenArray.FlattenArray failed", string(out))
+	}
+	if err := j.FlattenArray("a", -1); err != nil {
+		t.Fatal("TestFlattenArray.FlattenArray failed", err)
+	}
+	if out, _ := j.Export(""); string(out) != `{"a":[1,2,3,4,5,6],"b":1}` {
+		t.Fatal("TestFlattenArray.FlattenArray failed", string(out))
+	}
+	if err := j.FlattenArray("b", -1); err != ErrTypeMissmatch {
+		t.Fatal("TestFlattenArray.FlattenArray failed", err)
+	}
+}
+
+func TestEqual(t *testing.T) {
+
+	docs := []struct {
+		a, b  string
+		equal bool
+	}{
+		{`{"a":1,"b":2}`, `{"b":2,"a":1}`, true},
+		{`{"a":[1,{"b":null}]}`, `{"a":[1,{"b":null}]}`, true},
+		{`{"a":1}`, `{"a":1,"b":2}`, false},
+		{`[1,2]`, `[2,1]`, false},
+		{`{"a":1}`, `{"a":"1"}`, false},
+	}
+
+	for _, doc := range docs {
+		a, err := Unmarshal([]byte(doc.a))
+		if err != nil {
+			t.Fatal("TestEqual failed", err)
+		}
+		b, err := Unmarshal([]byte(doc.b))
+		if err != nil {
+			t.Fatal("TestEqual failed", err)
+		}
+		if a.Equal(b) != doc.equal || b.Equal(a) != doc.equal {
+			t.Fatal("TestEqual.Equal failed", doc.a, doc.b)
+		}
+	}
+}
+
+func TestAggregateTag(t *testing.T) {
+
+	const json = `{
+		"order" : {
+			"id": 7,
+			"items": [
+				{ "name": "bolt", "price": 1.5 },
+				{ "name": "nut", "price": 0.5 },
+				{ "name": "gear", "price": 4 }
+			]
+		}
+}`
+
+	type Order struct {
+		ID    int     `json:"id"`
+		Total float64 `jsonobj:"sum:items[*].price"`
+		Avg   float64 `jsonobj:"avg:items[*].price"`
+		Count int     `jsonobj:"count:items[*].price"`
+		Min   float64 `jsonobj:"min:items[*].price"`
+		Max   float64 `jsonobj:"max:items[*].price"`
+	}
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestAggregateTag failed", err)
+	}
+	order := Order{}
+	if err := j.Get("order", &order); err != nil {
+		t.Fatal("TestAggregateTag.Get failed", err)
+	}
+	if order.ID != 7 || order.Total != 6 ||
+		order.Avg != 2 || order.Count != 3 || order.Min != 0.5 || order.Max != 4 {
+		t.Fatal("TestAggregateTag.Get failed", order)
+	}
+
+	bad := struct {
+		Median float64 `jsonobj:"median:items[*].price"`
+	}{}
+	if err := j.Get("order", &bad); err != ErrInvalidTag {
+		t.Fatal("TestAggregateTag.Get failed", err)
+	}
+}
+
+func TestLayer(t *testing.T) {
+
+	const base = `{ "host": "localhost", "port": 80, "log": { "level": "info", "file": "a.log" }, "tags": [1, 2] }`
+	const env = `{ "host": "example.com", "log": { "level": "warn" }, "tags": [3] }`
+	const local = `{ "port": 8080, "log": { "file": null }, "debug": true }`
+
+	docs := []*JSON{}
+	for _, doc := range []string{base, env, local} {
+		j, err := Unmarshal([]byte(doc))
+		if err != nil {
+			t.Fatal("TestLayer failed", err)
+		}
+		docs = append(docs, j)
+	}
+
+	j, err := Layer(docs...)
+	if err != nil {
+		t.Fatal("TestLayer.Layer failed", err)
+	}
+	const want = `{"debug":true,"host":"example.com","log":{"level":"warn"},"port":8080,"tags":[3]}`
+	if out, _ := j.Export(""); string(out) != want {
+		t.Fatal("TestLayer.Layer failed", string(out))
+	}
+	if out, _ := docs[0].Export(""); string(out) != `{"host":"localhost","log":{"file":"a.log","level":"info"},"port":80,"tags":[1,2]}` {
+		t.Fatal("TestLayer.Layer modified input", string(out))
+	}
+
+	if j, err = LayerKeepNull(docs...); err != nil {
+		t.Fatal("TestLayer.LayerKeepNull failed", err)
+	}
+	if out, _ := j.Export(""); string(out) != `{"debug":true,"host":"example.com","log":{"file":null,"level":"warn"},"port":8080,"tags":[3]}` {
+		t.Fatal("TestLayer.LayerKeepNull failed", string(out))
+	}
+
+	if _, err := Layer(); err != ErrInvalidIn {
+		t.Fatal("TestLayer.Layer failed", err)
+	}
+}
+
+func TestGetMap(t *testing.T) {
+
+	const json = `{
+		"config": { "timeout": 30, "retries": 3 },
+		"names": { "1": "one", "2": "two" },
+		"lists": { "a": [1, 2], "b": [3] },
+		"scalar": 42
+}`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestGetMap failed", err)
+	}
+
+	config := map[string]int{}
+	if err := j.Get("config", &config); err != nil {
+		t.Fatal("TestGetMap.Get failed", err)
+	}
+	if len(config) != 2 || config["timeout"] != 30 || config["retries"] != 3 {
+		t.Fatal("TestGetMap.Get failed", config)
+	}
+
+	var names map[uint8]string
+	if err := j.Get("names", &names); err != nil {
+		t.Fatal("TestGetMap.Get failed", err)
+	}
+	if len(names) != 2 || names[1] != "one" || names[2] != "two" {
+		t.Fatal("TestGetMap.Get failed", names)
+	}
+
+	var lists map[string][]int
+	if err := j.Get("lists", &lists); err != nil {
+		t.Fatal("TestGetMap.Get failed", err)
+	}
+	if len(lists) != 2 || len(lists["a"]) != 2 || lists["a"][1] != 2 || lists["b"][0] != 3 {
+		t.Fatal("TestGetMap.Get failed", lists)
+	}
+
+	var ids map[int]int
+	if err := j.Get("config", &ids); err != ErrTypeMissmatch {
+		t.Fatal("TestGetMap.Get failed", err)
+	}
+	if err := j.Get("scalar", &config); err != ErrTypeMissmatch {
+		t.Fatal("TestGetMap.Get failed", err)
+	}
+}
+
+func TestGetInterface(t *testing.T) {
+
+	const json = `{ "a": 1, "b": "two", "c": [true, null], "d": { "e": 5 } }`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestGetInterface failed", err)
+	}
+
+	var a, b, c, d interface{}
+	for path, out := range map[string]*interface{}{"a": &a, "b": &b, "c": &c, "d": &d} {
+		if err := j.Get(path, out); err != nil {
+			t.Fatal("TestGetInterface.Get failed", err)
+		}
+	}
+	if a != 1.0 || b != "two" {
+		t.Fatal("TestGetInterface.Get failed", a, b)
+	}
+	if cv, ok := c.([]interface{}); !ok || len(cv) != 2 || cv[0] != true || cv[1] != nil {
+		t.Fatal("TestGetInterface.Get failed", c)
+	}
+	if dv, ok := d.(map[string]interface{}); !ok || dv["e"] != 5.0 {
+		t.Fatal("TestGetInterface.Get failed", d)
+	}
+
+	var s fmt.Stringer
+	if err := j.Get("b", &s); err != ErrTypeMissmatch {
+		t.Fatal("TestGetInterface.Get failed", err)
+	}
+}
+
+func TestClosedTag(t *testing.T) {
+
+	type Limits struct {
+		Max int `json:"max"`
+		Min int
+	}
+	type Config struct {
+		Name   string `json:"name"`
+		Limits Limits `json:"limits" jsonobj:",closed"`
+	}
+
+	j, err := Unmarshal([]byte(`{ "name": "a", "extra": 1, "limits": { "max": 9, "min": 1 } }`))
+	if err != nil {
+		t.Fatal("TestClosedTag failed", err)
+	}
+	cfg := Config{}
+	if err := j.Get("", &cfg); err != nil {
+		t.Fatal("TestClosedTag.Get failed", err)
+	}
+	if cfg.Name != "a" || cfg.Limits.Max != 9 || cfg.Limits.Min != 1 {
+		t.Fatal("TestClosedTag.Get failed", cfg)
+	}
+
+	j, err = Unmarshal([]byte(`{ "name": "a", "limits": { "max": 9, "mux": 1 } }`))
+	if err != nil {
+		t.Fatal("TestClosedTag failed", err)
+	}
+	if err := j.Get("", &cfg); !errors.Is(err, ErrUnknownField) || err.(*UnknownFieldError).Key != "mux" {
+		t.Fatal("TestClosedTag.Get failed", err)
+	}
+
+	var pcfg struct {
+		Limits *Limits `json:"limits" jsonobj:",closed"`
+	}
+	if err := j.Get("", &pcfg); !errors.Is(err, ErrUnknownField) || err.(*UnknownFieldError).Key != "mux" {
+		t.Fatal("TestClosedTag.Get failed", err)
+	}
+}
+
+func TestTxn(t *testing.T) {
+
+	const json = `{"a":1,"b":{"c":[1,2,3]}}`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestTxn failed", err)
+	}
+
+	txn := j.Begin()
+	if err := j.Set("a", "one"); err != nil {
+		t.Fatal("TestTxn.Set failed", err)
+	}
+	if err := j.Set("b.c[0]", 9); err != nil {
+		t.Fatal("TestTxn.Set failed", err)
+	}
+	if err := j.Delete("b.c[2]"); err != nil {
+		t.Fatal("TestTxn.Delete failed", err)
+	}
+	txn.Rollback()
+	if out, _ := j.Export(""); string(out) != json {
+		t.Fatal("TestTxn.Rollback failed", string(out))
+	}
+
+	txn = j.Begin()
+	if err := j.Delete("a"); err != nil {
+		t.Fatal("TestTxn.Delete failed", err)
+	}
+	txn.Commit()
+	txn.Rollback()
+	if out, _ := j.Export(""); string(out) != `{"b":{"c":[1,2,3]}}` {
+		t.Fatal("TestTxn.Commit failed", string(out))
+	}
+	if err := j.Delete("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestTxn.Delete failed", err)
+	}
+}
+
+func TestGetPointer(t *testing.T) {
+
+	const json = `{ "id": 7, "name": "Mirko", "inner": { "age": 42 } }`
+
+	type Inner struct {
+		Age *int `json:"age"`
+	}
+	type Outer struct {
+		ID    *int    `json:"id"`
+		Name  *string `json:"name"`
+		Note  *string `json:"note"`
+		Inner *Inner  `json:"inner"`
+		Other *Inner  `json:"other"`
+	}
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestGetPointer failed", err)
+	}
+
+	out := Outer{}
+	if err := j.Get("", &out); err != nil {
+		t.Fatal("TestGetPointer.Get failed", err)
+	}
+	if out.ID == nil || *out.ID != 7 || out.Name == nil || *out.Name != "Mirko" ||
+		out.Inner == nil || out.Inner.Age == nil || *out.Inner.Age != 42 {
+		t.Fatal("TestGetPointer.Get failed", out)
+	}
+	if out.Note != nil || out.Other != nil {
+		t.Fatal("TestGetPointer.Get failed", out)
+	}
+
+	var pp *Inner
+	if err := j.Get("inner", &pp); err != nil {
+		t.Fatal("TestGetPointer.Get failed", err)
+	}
+	if pp == nil || pp.Age == nil || *pp.Age != 42 {
+		t.Fatal("TestGetPointer.Get failed", pp)
+	}
+}
+
+func TestNull(t *testing.T) {
+
+	const json = `{ "a": null, "b": 1, "c": [null] }`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestNull failed", err)
+	}
+
+	if null, err := j.IsNull("a"); err != nil || !null {
+		t.Fatal("TestNull.IsNull failed", null, err)
+	}
+	if null, err := j.IsNull("c[0]"); err != nil || !null {
+		t.Fatal("TestNull.IsNull failed", null, err)
+	}
+	if null, err := j.IsNull("b"); err != nil || null {
+		t.Fatal("TestNull.IsNull failed", null, err)
+	}
+	if _, err := j.IsNull("d"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestNull.IsNull failed", err)
+	}
+
+	n := 5
+	p := &n
+	if err := j.Get("a", &p); err != nil || p != nil {
+		t.Fatal("TestNull.Get failed", p, err)
+	}
+	var i interface{} = 5
+	if err := j.Get("a", &i); err != nil || i != nil {
+		t.Fatal("TestNull.Get failed", i, err)
+	}
+	if err := j.Get("a", &n); err != nil || n != 5 {
+		t.Fatal("TestNull.Get failed", n, err)
+	}
+}
+
+func TestRange(t *testing.T) {
+
+	const json = `{ "planets": [ { "name": "Saturn" }, { "name": "Uranus" }, { "name": "Neptune" } ] }`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestRange failed", err)
+	}
+
+	names := []string{}
+	err = j.Range("planets", func(index int, item *JSON) error {
+		var name string
+		if err := item.Get("name", &name); err != nil {
+			return err
+		}
+		names = append(names, strconv.Itoa(index)+name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("TestRange.Range failed", err)
+	}
+	if strings.Join(names, ",") != "0Saturn,1Uranus,2Neptune" {
+		t.Fatal("TestRange.Range failed", names)
+	}
+
+	count := 0
+	err = j.Range("planets", func(index int, item *JSON) error {
+		if count++; index == 1 {
+			return ErrOutOfRange
+		}
+		return nil
+	})
+	if err != ErrOutOfRange || count != 2 {
+		t.Fatal("TestRange.Range failed", err, count)
+	}
+	if err := j.Range("planets[0]", nil); err != ErrTypeMissmatch {
+		t.Fatal("TestRange.Range failed", err)
+	}
+}
+
+func TestForEach(t *testing.T) {
+
+	const json = `{ "moons": { "Saturn": 62, "Uranus": 27, "Mars": 2 }, "list": [] }`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestForEach failed", err)
+	}
+
+	result := []string{}
+	err = j.ForEach("moons", func(key string, value *JSON) error {
+		var n int
+		if err := value.Get("", &n); err != nil {
+			return err
+		}
+		result = append(result, key+strconv.Itoa(n))
+		return nil
+	})
+	if err != nil {
+		t.Fatal("TestForEach.ForEach failed", err)
+	}
+	if strings.Join(result, ",") != "Mars2,Saturn62,Uranus27" {
+		t.Fatal("TestForEach.ForEach failed", result)
+	}
+
+	err = j.ForEach("moons", func(key string, value *JSON) error {
+		return ErrNotFound
+	})
+	if err != ErrNotFound {
+		t.Fatal("TestForEach.ForEach failed", err)
+	}
+	if err := j.ForEach("list", nil); err != ErrTypeMissmatch {
+		t.Fatal("TestForEach.ForEach failed", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+
+	j := NewObject()
+	if err := j.Set("name", "Saturn"); err != nil {
+		t.Fatal("TestNew.Set failed", err)
+	}
+	if err := j.Set("moons", []string{}); err != nil {
+		t.Fatal("TestNew.Set failed", err)
+	}
+	if err := j.Append("moons", "Titan"); err != nil {
+		t.Fatal("TestNew.Append failed", err)
+	}
+	if err := j.Append("moons", "Rhea"); err != nil {
+		t.Fatal("TestNew.Append failed", err)
+	}
+	if err := j.Append("name", "Rhea"); err != ErrTypeMissmatch {
+		t.Fatal("TestNew.Append failed", err)
+	}
+	if out, _ := j.Export(""); string(out) != `{"moons":["Titan","Rhea"],"name":"Saturn"}` {
+		t.Fatal("TestNew.Export failed", string(out))
+	}
+
+	a := NewArray()
+	if err := a.Append("", map[string]int{"moons": 62}); err != nil {
+		t.Fatal("TestNew.Append failed", err)
+	}
+	if err := a.Set("[0].rings", true); err != nil {
+		t.Fatal("TestNew.Set failed", err)
+	}
+	if out, _ := a.Export(""); string(out) != `[{"moons":62,"rings":true}]` {
+		t.Fatal("TestNew.Export failed", string(out))
+	}
+}
+
+func TestFromStruct(t *testing.T) {
+
+	type Planet struct {
+		Name  string   `json:"name"`
+		Moons []string `json:"moons"`
+	}
+
+	j, err := FromStruct(Planet{"Saturn", []string{"Titan", "Rhea"}})
+	if err != nil {
+		t.Fatal("TestFromStruct.FromStruct failed", err)
+	}
+	if err := j.Set("moons[1]", "Dione"); err != nil {
+		t.Fatal("TestFromStruct.Set failed", err)
+	}
+	if out, _ := j.Export(""); string(out) != `{"moons":["Titan","Dione"],"name":"Saturn"}` {
+		t.Fatal("TestFromStruct.Export failed", string(out))
+	}
+
+	if _, err := FromStruct(make(chan int)); err == nil {
+		t.Fatal("TestFromStruct.FromStruct failed", err)
+	}
+}
+
+func TestString(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "b": [1, 2], "a": "x" }`))
+	if err != nil {
+		t.Fatal("TestString failed", err)
+	}
+	if s := fmt.Sprint(j); s != `{"a":"x","b":[1,2]}` {
+		t.Fatal("TestString.String failed", s)
+	}
+
+	var n *JSON
+	if s := n.String(); s != "<nil>" {
+		t.Fatal("TestString.String failed", s)
+	}
+	if s := (&JSON{intf: math.NaN()}).String(); s != "<invalid json>" {
+		t.Fatal("TestString.String failed", s)
+	}
+}
+
+func TestGetWithTag(t *testing.T) {
+
+	type Config struct {
+		Timeout int    `conf:"timeout" json:"t"`
+		Host    string `conf:"host"`
+		Skip    string `conf:"-"`
+		Port    int
+	}
+
+	j, err := Unmarshal([]byte(`{ "timeout": 30, "t": 10, "host": "localhost", "skip": "x", "port": 80 }`))
+	if err != nil {
+		t.Fatal("TestGetWithTag failed", err)
+	}
+
+	cfg := Config{}
+	if err := j.GetWithTag("", "conf", &cfg); err != nil {
+		t.Fatal("TestGetWithTag.GetWithTag failed", err)
+	}
+	if cfg.Timeout != 30 || cfg.Host != "localhost" || cfg.Skip != "" || cfg.Port != 80 {
+		t.Fatal("TestGetWithTag.GetWithTag failed", cfg)
+	}
+
+	cfg = Config{}
+	if err := j.GetWithTag("", "", &cfg); err != nil {
+		t.Fatal("TestGetWithTag.GetWithTag failed", err)
+	}
+	if cfg.Timeout != 10 || cfg.Host != "localhost" || cfg.Skip != "x" {
+		t.Fatal("TestGetWithTag.GetWithTag failed", cfg)
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+
+	type Base struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	type Meta struct {
+		Owner string `json:"owner"`
+	}
+	type Derived struct {
+		Base
+		*Meta
+		Name  string `json:"name"`
+		Extra int    `json:"extra"`
+	}
+	type Cyclic struct {
+		*Cyclic
+		ID int `json:"id"`
+	}
+
+	j, err := Unmarshal([]byte(`{ "id": 7, "name": "outer", "owner": "me", "extra": 1 }`))
+	if err != nil {
+		t.Fatal("TestEmbedded failed", err)
+	}
+
+	d := Derived{}
+	if err := j.Get("", &d); err != nil {
+		t.Fatal("TestEmbedded.Get failed", err)
+	}
+	if d.ID != 7 || d.Base.Name != "" || d.Name != "outer" || d.Extra != 1 ||
+		d.Meta == nil || d.Owner != "me" {
+		t.Fatal("TestEmbedded.Get failed", d)
+	}
+
+	c := Cyclic{}
+	if err := j.Get("", &c); err != nil {
+		t.Fatal("TestEmbedded.Get failed", err)
+	}
+	if c.ID != 7 || c.Cyclic != nil {
+		t.Fatal("TestEmbedded.Get failed", c)
+	}
+
+	closed := struct {
+		D Derived `json:"d" jsonobj:",closed"`
+	}{}
+	if err := (&JSON{intf: map[string]interface{}{"d": j.intf}}).Get("", &closed); err != nil {
+		t.Fatal("TestEmbedded.Get failed", err)
+	}
+}
+
+func TestMustGet(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "name": "Saturn", "moons": 62, "mass": 5.68 }`))
+	if err != nil {
+		t.Fatal("TestMustGet failed", err)
+	}
+	if j.MustGetString("name") != "Saturn" || j.MustGetInt("moons") != 62 {
+		t.Fatal("TestMustGet.MustGet failed")
+	}
+
+	defer func() {
+		r := recover()
+		if r != `jsonobj: MustGet("mass"): json value truncated or overflowed in output` {
+			t.Fatal("TestMustGet.MustGet failed", r)
+		}
+	}()
+	j.MustGetInt("mass")
+}
+
+func TestGetOr(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "name": "Saturn", "moons": 62, "mass": 5.68, "rings": true, "none": null }`))
+	if err != nil {
+		t.Fatal("TestGetOr failed", err)
+	}
+
+	if v := j.GetStringOr("name", "x"); v != "Saturn" {
+		t.Fatal("TestGetOr.GetStringOr failed", v)
+	}
+	if v := j.GetStringOr("moons", "x"); v != "x" {
+		t.Fatal("TestGetOr.GetStringOr failed", v)
+	}
+	if v := j.GetIntOr("moons", 1); v != 62 {
+		t.Fatal("TestGetOr.GetIntOr failed", v)
+	}
+	if v := j.GetIntOr("mass", 1); v != 1 {
+		t.Fatal("TestGetOr.GetIntOr failed", v)
+	}
+	if v := j.GetBoolOr("rings", false); v != true {
+		t.Fatal("TestGetOr.GetBoolOr failed", v)
+	}
+	if v := j.GetStringOr("none", "x"); v != "x" {
+		t.Fatal("TestGetOr.GetStringOr failed", v)
+	}
+	if v := j.GetBoolOr("missing", true); v != true {
+		t.Fatal("TestGetOr.GetBoolOr failed", v)
+	}
+	if v := j.GetFloat64Or("mass", 0); v != 5.68 {
+		t.Fatal("TestGetOr.GetFloat64Or failed", v)
+	}
+	if v := j.GetFloat64Or("name..x", 1.5); v != 1.5 {
+		t.Fatal("TestGetOr.GetFloat64Or failed", v)
+	}
+}
+
+func TestGetWildcard(t *testing.T) {
+
+	const json = `{
+		"planets": [
+			{ "name": "Saturn", "moons": [ { "name": "Titan" }, { "name": "Rhea" } ] },
+			{ "name": "Uranus", "moons": [ { "name": "Miranda" } ] },
+			{ "name": "Mercury" }
+		]
+}`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestGetWildcard failed", err)
+	}
+
+	var names []string
+	if err := j.Get("planets[*].name", &names); err != nil {
+		t.Fatal("TestGetWildcard.Get failed", err)
+	}
+	if strings.Join(names, ",") != "Saturn,Uranus,Mercury" {
+		t.Fatal("TestGetWildcard.Get failed", names)
+	}
+	if err := j.Get("planets[*].moons[*].name", &names); err != nil {
+		t.Fatal("TestGetWildcard.Get failed", err)
+	}
+	if strings.Join(names, ",") != "Titan,Rhea,Miranda" {
+		t.Fatal("TestGetWildcard.Get failed", names)
+	}
+
+	var name string
+	if err := j.Get("planets[*].name", &name); err != ErrInvalidOut {
+		t.Fatal("TestGetWildcard.Get failed", err)
+	}
+	if err := j.Get("moons[*].name", &names); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestGetWildcard.Get failed", err)
+	}
+	if err := j.Set("planets[*].name", "Pluto"); err != ErrInvalidPath {
+		t.Fatal("TestGetWildcard.Set failed", err)
+	}
+
+	if j, err = Unmarshal([]byte(`{ "rows": [ [ 1, 2, 3 ], [ 4 ], [ 5, 6, 7 ] ] }`)); err != nil {
+		t.Fatal("TestGetWildcard failed", err)
+	}
+	var values []int
+	if err := j.Get("rows[*][2]", &values); err != nil || fmt.Sprint(values) != "[3 7]" {
+		t.Fatal("TestGetWildcard.Get failed", values, err)
+	}
+	if st, err := j.Stats("rows[*][1]"); err != nil || st.Count != 2 || st.Sum != 8 {
+		t.Fatal("TestGetWildcard.Stats failed", st, err)
+	}
+	if j, err = Unmarshal([]byte(`{ "a": [ { "b": [ 0, [ 1, 2 ] ] }, { "b": [ 0 ] } ] }`)); err != nil {
+		t.Fatal("TestGetWildcard failed", err)
+	}
+	if err := j.Get("a[*].b[1][*]", &values); err != nil || fmt.Sprint(values) != "[1 2]" {
+		t.Fatal("TestGetWildcard.Get failed", values, err)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+
+	const json = `{
+		"name": "Saturn",
+		"moons": [ { "name": "Titan", "radius": 2575 }, [ true, null ] ],
+		"rings": {},
+		"tags": [],
+		"a.b": { "c[0]": 1 }
+}`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestFlatten failed", err)
+	}
+
+	flat := j.Flatten()
+	want := []string{"moons[0].name", "moons[0].radius", "moons[1][0]", "moons[1][1]", "name", "rings", "tags",
+		`["a.b"]["c[0]"]`}
+	if len(flat) != len(want) {
+		t.Fatal("TestFlatten.Flatten failed", flat)
+	}
+	for _, path := range want {
+		v, ok := flat[path]
+		if !ok {
+			t.Fatal("TestFlatten.Flatten failed", path)
+		}
+		var got interface{}
+		if err := j.Get(path, &got); err != nil {
+			t.Fatal("TestFlatten.Get failed", path, err)
+		}
+		if !equal(v, got) {
+			t.Fatal("TestFlatten.Flatten failed", path, v, got)
+		}
+	}
+
+	if flat := (&JSON{intf: 42.0}).Flatten(); len(flat) != 1 || flat[""] != 42.0 {
+		t.Fatal("TestFlatten.Flatten failed", flat)
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+
+	const json = `{"moons":[{"name":"Titan","radius":2575},[true,null]],"name":"Saturn","rings":{},"tags":[]}`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestUnflatten failed", err)
+	}
+	u, err := Unflatten(j.Flatten())
+	if err != nil {
+		t.Fatal("TestUnflatten.Unflatten failed", err)
+	}
+	if !u.Equal(j) {
+		t.Fatal("TestUnflatten.Unflatten failed", u)
+	}
+
+	u, err = Unflatten(map[string]interface{}{
+		"db.hosts[2]": "c",
+		"db.hosts[0]": "a",
+		"db.port":     5432,
+		"[0]":         1,
+	})
+	if err != ErrConflict {
+		t.Fatal("TestUnflatten.Unflatten failed", err)
+	}
+	u, err = Unflatten(map[string]interface{}{
+		"db.hosts[2]": "c",
+		"db.hosts[0]": "a",
+		"db.port":     5432,
+	})
+	if err != nil {
+		t.Fatal("TestUnflatten.Unflatten failed", err)
+	}
+	if s := u.String(); s != `{"db":{"hosts":["a",null,"c"],"port":5432}}` {
+		t.Fatal("TestUnflatten.Unflatten failed", s)
+	}
+
+	if _, err := Unflatten(map[string]interface{}{"a": 1, "a.b": 2}); err != ErrConflict {
+		t.Fatal("TestUnflatten.Unflatten failed", err)
+	}
+	if _, err := Unflatten(map[string]interface{}{"a.b": 1, "a": 2}); err != ErrConflict {
+		t.Fatal("TestUnflatten.Unflatten failed", err)
+	}
+	if _, err := Unflatten(map[string]interface{}{"a": nil, "a.b": 1}); err != ErrConflict {
+		t.Fatal("TestUnflatten.Unflatten failed", err)
+	}
+	if _, err := Unflatten(map[string]interface{}{"a": nil, `["a"]`: nil}); err != ErrConflict {
+		t.Fatal("TestUnflatten.Unflatten failed", err)
+	}
+	if _, err := Unflatten(map[string]interface{}{"": nil, "[0]": 1}); err != ErrConflict {
+		t.Fatal("TestUnflatten.Unflatten failed", err)
+	}
+	if u, err = Unflatten(map[string]interface{}{"l[2]": 1, "l[0]": nil}); err != nil || u.String() != `{"l":[null,null,1]}` {
+		t.Fatal("TestUnflatten.Unflatten failed", u, err)
+	}
+	if _, err := Unflatten(map[string]interface{}{"a..b": 1}); err != ErrInvalidPath {
+		t.Fatal("TestUnflatten.Unflatten failed", err)
+	}
+}
+
+func TestInsert(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": [1, 2, 3], "b": "x" }`))
+	if err != nil {
+		t.Fatal("TestInsert failed", err)
+	}
+
+	if err := j.Insert("a", 1, "one"); err != nil {
+		t.Fatal("TestInsert.Insert failed", err)
+	}
+	if err := j.Insert("a", 4, "end"); err != nil {
+		t.Fatal("TestInsert.Insert failed", err)
+	}
+	if err := j.Insert("a", 0, map[string]int{"z": 0}); err != nil {
+		t.Fatal("TestInsert.Insert failed", err)
+	}
+	if err := j.Insert("a", -1, "last"); err != nil {
+		t.Fatal("TestInsert.Insert failed", err)
+	}
+	if s := j.String(); s != `{"a":[{"z":0},1,"one",2,3,"last","end"],"b":"x"}` {
+		t.Fatal("TestInsert.Insert failed", s)
+	}
+
+	if err := j.Insert("a", 8, 0); err != ErrOutOfRange {
+		t.Fatal("TestInsert.Insert failed", err)
+	}
+	if err := j.Insert("a", -8, 0); err != ErrOutOfRange {
+		t.Fatal("TestInsert.Insert failed", err)
+	}
+	if err := j.Insert("b", 0, 0); err != ErrTypeMissmatch {
+		t.Fatal("TestInsert.Insert failed", err)
+	}
+}
+
+func TestRemoveAt(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": [1, 2, 3, 4, 5], "b": "x" }`))
+	if err != nil {
+		t.Fatal("TestRemoveAt failed", err)
+	}
+
+	if err := j.RemoveAt("a", 1); err != nil {
+		t.Fatal("TestRemoveAt.RemoveAt failed", err)
+	}
+	if err := j.RemoveAt("a", -1); err != nil {
+		t.Fatal("TestRemoveAt.RemoveAt failed", err)
+	}
+	if err := j.RemoveAt("a", 0); err != nil {
+		t.Fatal("TestRemoveAt.RemoveAt failed", err)
+	}
+	if s := j.String(); s != `{"a":[3,4],"b":"x"}` {
+		t.Fatal("TestRemoveAt.RemoveAt failed", s)
+	}
+
+	if err := j.RemoveAt("a", 2); err != ErrOutOfRange {
+		t.Fatal("TestRemoveAt.RemoveAt failed", err)
+	}
+	if err := j.RemoveAt("a", -3); err != ErrOutOfRange {
+		t.Fatal("TestRemoveAt.RemoveAt failed", err)
+	}
+	if err := j.RemoveAt("b", 0); err != ErrTypeMissmatch {
+		t.Fatal("TestRemoveAt.RemoveAt failed", err)
+	}
+}
+
+func TestSetThroughScalar(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": 1, "b": [ "x" ] }`))
+	if err != nil {
+		t.Fatal("TestSetThroughScalar failed", err)
+	}
+
+	for _, path := range []string{"a.b", "a[0]", "b[0].c", "b[0][0]", "[0]"} {
+		if err := j.Set(path, 2); !errors.Is(err, ErrNotFound) {
+			t.Fatal("TestSetThroughScalar.Set failed", path, err)
+		}
+	}
+	if s := j.String(); s != `{"a":1,"b":["x"]}` {
+		t.Fatal("TestSetThroughScalar.Set failed", s)
+	}
+	if err := (&JSON{intf: 1.0}).putPath(Path{{Key: "a"}}, 2.0); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestSetThroughScalar.putPath failed", err)
+	}
+}
+
+func TestSetOutOfRange(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "items": [ 1, 2 ], "grid": [ [ 1 ] ] }`))
+	if err != nil {
+		t.Fatal("TestSetOutOfRange failed", err)
+	}
+
+	for _, path := range []string{"items[2]", "items[99]", "grid[0][1]", "grid[1][0]"} {
+		if err := j.Set(path, 3); !errors.Is(err, ErrOutOfRange) {
+			t.Fatal("TestSetOutOfRange.Set failed", path, err)
+		}
+	}
+	if err := j.Set("items[1]", 3); err != nil {
+		t.Fatal("TestSetOutOfRange.Set failed", err)
+	}
+	if s := j.String(); s != `{"grid":[[1]],"items":[1,3]}` {
+		t.Fatal("TestSetOutOfRange.Set failed", s)
+	}
+}
+
+func TestCopy(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "defaults": { "port": 80, "hosts": [ "a" ] } }`))
+	if err != nil {
+		t.Fatal("TestCopy failed", err)
+	}
+
+	if err := j.Copy("defaults", "envs.prod[1].config"); err != nil {
+		t.Fatal("TestCopy.Copy failed", err)
+	}
+	if err := j.Set("envs.prod[1].config.hosts[0]", "b"); err != nil {
+		t.Fatal("TestCopy.Set failed", err)
+	}
+	if err := j.Set("defaults.port", 8080); err != nil {
+		t.Fatal("TestCopy.Set failed", err)
+	}
+	const want = `{"defaults":{"hosts":["a"],"port":8080},"envs":{"prod":[null,{"config":{"hosts":["b"],"port":80}}]}}`
+	if s := j.String(); s != want {
+		t.Fatal("TestCopy.Copy failed", s)
+	}
+
+	if err := j.Copy("missing", "x"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestCopy.Copy failed", err)
+	}
+	if err := j.Copy("defaults", "defaults.port.x"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestCopy.Copy failed", err)
+	}
+}
+
+func TestMove(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": { "b": [ 1, 2, 3 ] }, "c": 4 }`))
+	if err != nil {
+		t.Fatal("TestMove failed", err)
+	}
+
+	if err := j.Move("a.b[0]", "a.b[1]"); err != nil {
+		t.Fatal("TestMove.Move failed", err)
+	}
+	if err := j.Move("c", "d.e"); err != nil {
+		t.Fatal("TestMove.Move failed", err)
+	}
+	if err := j.Move("a", "a"); err != nil {
+		t.Fatal("TestMove.Move failed", err)
+	}
+	if s := j.String(); s != `{"a":{"b":[2,1]},"d":{"e":4}}` {
+		t.Fatal("TestMove.Move failed", s)
+	}
+
+	if err := j.Move("a", "a.b[0].x"); err != ErrInvalidPath {
+		t.Fatal("TestMove.Move failed", err)
+	}
+	if err := j.Move("a.b[1]", "d.e.f"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestMove.Move failed", err)
+	}
+	if err := j.Move("d", "a.b[5]"); !errors.Is(err, ErrOutOfRange) {
+		t.Fatal("TestMove.Move failed", err)
+	}
+	if err := j.Move("x", "y"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestMove.Move failed", err)
+	}
+	if err := j.Move("a.b[1]", "a.b[0].x"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestMove.Move failed", err)
+	}
+	if s := j.String(); s != `{"a":{"b":[2,1]},"d":{"e":4}}` {
+		t.Fatal("TestMove.Move failed", s)
+	}
+}
+
+func TestGetTime(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "created": "2018-06-01T12:30:00.5+02:00", "bad": "yesterday", "b": true }`))
+	if err != nil {
+		t.Fatal("TestGetTime failed", err)
+	}
+
+	var created time.Time
+	if err := j.Get("created", &created); err != nil {
+		t.Fatal("TestGetTime.Get failed", err)
+	}
+	if !created.Equal(time.Date(2018, 6, 1, 10, 30, 0, 5e8, time.UTC)) {
+		t.Fatal("TestGetTime.Get failed", created)
+	}
+
+	s := struct {
+		Created *time.Time `json:"created"`
+	}{}
+	if err := j.Get("", &s); err != nil || s.Created == nil || !s.Created.Equal(created) {
+		t.Fatal("TestGetTime.Get failed", s, err)
+	}
+
+	if err := j.Get("bad", &created); err == nil {
+		t.Fatal("TestGetTime.Get failed", created)
+	} else if _, ok := err.(*time.ParseError); !ok {
+		t.Fatal("TestGetTime.Get failed", err)
+	}
+	if err := j.Get("b", &created); err != ErrInvalidOut {
+		t.Fatal("TestGetTime.Get failed", err)
+	}
+}
+
+// hexColor is a color decoded from a "#rrggbb" string.
+type hexColor [3]byte
+
+func (c *hexColor) UnmarshalText(text []byte) error {
+
+	if len(text) != 7 || text[0] != '#' {
+		return errors.New("invalid color")
+	}
+	for i := range c {
+		v, err := strconv.ParseUint(string(text[1+i*2:3+i*2]), 16, 8)
+		if err != nil {
+			return err
+		}
+		c[i] = byte(v)
+	}
+	return nil
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "fg": "#ff8000", "bg": "red", "palette": ["#000000", "#ffffff"] }`))
+	if err != nil {
+		t.Fatal("TestTextUnmarshaler failed", err)
+	}
+
+	var fg hexColor
+	if err := j.Get("fg", &fg); err != nil || fg != (hexColor{0xff, 0x80, 0}) {
+		t.Fatal("TestTextUnmarshaler.Get failed", fg, err)
+	}
+
+	var palette []*hexColor
+	if err := j.Get("palette", &palette); err != nil || len(palette) != 2 || *palette[1] != (hexColor{0xff, 0xff, 0xff}) {
+		t.Fatal("TestTextUnmarshaler.Get failed", palette, err)
+	}
+
+	s := struct {
+		Fg hexColor
+		Bg *hexColor
+	}{}
+	if err := j.Get("", &s); err == nil || err.Error() != "invalid color" {
+		t.Fatal("TestTextUnmarshaler.Get failed", s, err)
+	}
+}
+
+// point is decoded from an Object of polar coordinates into cartesian ones.
+type point struct {
+	X, Y float64
+}
+
+func (p *point) UnmarshalJSON(b []byte) error {
+
+	var polar struct {
+		R     float64 `json:"r"`
+		Theta float64 `json:"theta"`
+	}
+	if err := json.Unmarshal(b, &polar); err != nil {
+		return err
+	}
+	p.X = polar.R * math.Cos(polar.Theta)
+	p.Y = polar.R * math.Sin(polar.Theta)
+	return nil
+}
+
+func TestJSONUnmarshaler(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "points": [{ "r": 2, "theta": 0 }, { "r": 1, "theta": 1.5707963267948966 }], "bad": "origin" }`))
+	if err != nil {
+		t.Fatal("TestJSONUnmarshaler failed", err)
+	}
+
+	var points []point
+	if err := j.Get("points", &points); err != nil || len(points) != 2 {
+		t.Fatal("TestJSONUnmarshaler.Get failed", points, err)
+	}
+	if points[0].X != 2 || points[0].Y != 0 || math.Abs(points[1].X) > 1e-9 || points[1].Y != 1 {
+		t.Fatal("TestJSONUnmarshaler.Get failed", points)
+	}
+
+	var p *point
+	if err := j.Get("points[0]", &p); err != nil || p == nil || p.X != 2 {
+		t.Fatal("TestJSONUnmarshaler.Get failed", p, err)
+	}
+
+	if err := j.Get("bad", &p); err == nil {
+		t.Fatal("TestJSONUnmarshaler.Get failed", p)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+
+	a, err := Unmarshal([]byte(`{ "b": [1.0, "<x>", { "z": 1e2, "a": null }], "a": true }`))
+	if err != nil {
+		t.Fatal("TestCanonical failed", err)
+	}
+	b, err := Unmarshal([]byte(`{"a":true,"b":[1,"<x>",{"a":null,"z":100}]}`))
+	if err != nil {
+		t.Fatal("TestCanonical failed", err)
+	}
+
+	ca, err := a.Canonical()
+	if err != nil {
+		t.Fatal("TestCanonical.Canonical failed", err)
+	}
+	cb, err := b.Canonical()
+	if err != nil {
+		t.Fatal("TestCanonical.Canonical failed", err)
+	}
+	if !bytes.Equal(ca, cb) || string(ca) != `{"a":true,"b":[1,"<x>",{"a":null,"z":100}]}` {
+		t.Fatal("TestCanonical.Canonical failed", string(ca), string(cb))
+	}
+}
+
+func TestGetBytes(t *testing.T) {
+
+	data := []byte{0, 1, 2, 0xfe, 0xff, '"', '\n'}
+	j := NewObject()
+	if err := j.Set("data", data); err != nil {
+		t.Fatal("TestGetBytes.Set failed", err)
+	}
+
+	b, err := j.GetBytes("data")
+	if err != nil || !bytes.Equal(b, data) {
+		t.Fatal("TestGetBytes.GetBytes failed", b, err)
+	}
+
+	var out []byte
+	if err := j.Get("data", &out); err != nil || !bytes.Equal(out, data) {
+		t.Fatal("TestGetBytes.Get failed", out, err)
+	}
+
+	if err := j.Set("bad", "not base64!"); err != nil {
+		t.Fatal("TestGetBytes.Set failed", err)
+	}
+	if _, err := j.GetBytes("bad"); err == nil {
+		t.Fatal("TestGetBytes.GetBytes failed")
+	}
+	if err := j.Set("n", 42); err != nil {
+		t.Fatal("TestGetBytes.Set failed", err)
+	}
+	if _, err := j.GetBytes("n"); err != ErrTypeMissmatch {
+		t.Fatal("TestGetBytes.GetBytes failed", err)
+	}
+	if err := j.Set("l", []int{1, 2, 3}); err != nil {
+		t.Fatal("TestGetBytes.Set failed", err)
+	}
+	if b, err := j.GetBytes("l"); err != ErrTypeMissmatch {
+		t.Fatal("TestGetBytes.GetBytes failed", b, err)
+	}
+}
+
+func TestParent(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": { "b": [ { "c": 1 }, [ 2 ] ] } }`))
+	if err != nil {
+		t.Fatal("TestParent failed", err)
+	}
+
+	tests := map[string]string{
+		"a":           "",
+		"a.b":         "a",
+		"a.b[0]":      "a.b",
+		"a.b[0].c":    "a.b[0]",
+		"a.b[1][0]":   "a.b[1]",
+		"a.b.[1].[0]": "a.b[1]",
+	}
+	for path, want := range tests {
+		parent, err := j.Parent(path)
+		if err != nil || parent != want {
+			t.Fatal("TestParent.Parent failed", path, parent, err)
+		}
+	}
+
+	if _, err := j.Parent(""); err != ErrInvalidPath {
+		t.Fatal("TestParent.Parent failed", err)
+	}
+	if _, err := j.Parent("a.x"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestParent.Parent failed", err)
+	}
+}
+
+func TestGetPointerSlice(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "items": [ { "name": "Mirko", "age": 42 }, null, { "name": "Mirjana" } ] }`))
+	if err != nil {
+		t.Fatal("TestGetPointerSlice failed", err)
+	}
+
+	type Item struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	var items []*Item
+	if err := j.Get("items", &items); err != nil {
+		t.Fatal("TestGetPointerSlice.Get failed", err)
+	}
+	if len(items) != 3 || items[0] == nil || items[2] == nil || items[1] != nil {
+		t.Fatal("TestGetPointerSlice.Get failed", items)
+	}
+	if *items[0] != (Item{"Mirko", 42}) || *items[2] != (Item{"Mirjana", 0}) {
+		t.Fatal("TestGetPointerSlice.Get failed", *items[0], *items[2])
+	}
+}
+
+func TestDeleteIf(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "items": [ { "id": 0, "done": true }, { "id": 1 }, { "id": 2, "done": true }, { "id": 3, "done": false } ] }`))
+	if err != nil {
+		t.Fatal("TestDeleteIf failed", err)
+	}
+
+	indexes := []int{}
+	if err := j.DeleteIf("items", func(index int, item *JSON) bool {
+		indexes = append(indexes, index)
+		return item.GetBoolOr("done", false)
+	}); err != nil {
+		t.Fatal("TestDeleteIf.DeleteIf failed", err)
+	}
+	if fmt.Sprint(indexes) != "[0 1 2 3]" {
+		t.Fatal("TestDeleteIf.DeleteIf failed", indexes)
+	}
+	if s := j.String(); s != `{"items":[{"id":1},{"done":false,"id":3}]}` {
+		t.Fatal("TestDeleteIf.DeleteIf failed", s)
+	}
+	if id := j.MustGetInt("items[1].id"); id != 3 {
+		t.Fatal("TestDeleteIf.DeleteIf failed", id)
+	}
+
+	if err := j.DeleteIf("items[0]", func(int, *JSON) bool { return true }); err != ErrTypeMissmatch {
+		t.Fatal("TestDeleteIf.DeleteIf failed", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "planets": [ { "name": "Mercury", "moons": 0 }, 42, { "name": "Mars", "moons": 2 }, { "name": "Venus", "moons": 0 }, { "name": "Pluto" } ] }`))
+	if err != nil {
+		t.Fatal("TestSelect failed", err)
+	}
+
+	items, err := j.Select("planets", "moons", 0)
+	if err != nil || len(items) != 2 {
+		t.Fatal("TestSelect.Select failed", items, err)
+	}
+	if items[0].MustGetString("name") != "Mercury" || items[1].MustGetString("name") != "Venus" {
+		t.Fatal("TestSelect.Select failed", items)
+	}
+
+	if items, err = j.Select("planets", "name", "Jupiter"); err != nil || len(items) != 0 {
+		t.Fatal("TestSelect.Select failed", items, err)
+	}
+	if _, err = j.Select("planets[0]", "name", "Mercury"); err != ErrTypeMissmatch {
+		t.Fatal("TestSelect.Select failed", err)
+	}
+}
+
+func TestRawMessage(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "kind": "circle", "spec": { "r": 2, "center": [ 0, 1 ] }, "tags": [ "a", "b" ] }`))
+	if err != nil {
+		t.Fatal("TestRawMessage failed", err)
+	}
+
+	out := struct {
+		Kind string          `json:"kind"`
+		Spec json.RawMessage `json:"spec"`
+		Tags json.RawMessage `json:"tags"`
+	}{}
+	if err := j.Get("", &out); err != nil {
+		t.Fatal("TestRawMessage.Get failed", err)
+	}
+	if out.Kind != "circle" || string(out.Spec) != `{"center":[0,1],"r":2}` || string(out.Tags) != `["a","b"]` {
+		t.Fatal("TestRawMessage.Get failed", out.Kind, string(out.Spec), string(out.Tags))
+	}
+
+	var raw json.RawMessage
+	if err := j.Get("spec.r", &raw); err != nil || string(raw) != "2" {
+		t.Fatal("TestRawMessage.Get failed", string(raw), err)
+	}
+}
+
+func TestCountLeaves(t *testing.T) {
+
+	tests := map[string]int{
+		`42`:                    1,
+		`null`:                  1,
+		`{}`:                    0,
+		`[ [], {} ]`:            0,
+		`{ "a": 1, "b": null }`: 2,
+		`{ "a": [ 1, [ 2, {} ], { "b": "c", "d": [ true ] } ] }`: 4,
+	}
+	for doc, want := range tests {
+		j, err := Unmarshal([]byte(doc))
+		if err != nil {
+			t.Fatal("TestCountLeaves failed", err)
+		}
+		if n := j.CountLeaves(); n != want {
+			t.Fatal("TestCountLeaves.CountLeaves failed", doc, n)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+
+	tests := map[string]int{
+		`"x"`:                           0,
+		`{}`:                            1,
+		`{ "a": 1, "b": "c" }`:          1,
+		`[ 1, [ 2 ], { "a": [ {} ] } ]`: 4,
+		strings.Repeat("[", 100) + strings.Repeat("]", 100): 100,
+	}
+	for doc, want := range tests {
+		j, err := Unmarshal([]byte(doc))
+		if err != nil {
+			t.Fatal("TestMaxDepth failed", err)
+		}
+		if d := j.MaxDepth(); d != want {
+			t.Fatal("TestMaxDepth.MaxDepth failed", doc, d)
+		}
+	}
+}
+
+func TestUnmarshalLimited(t *testing.T) {
+
+	doc := []byte(`{ "a": [ { "b": "[[{{\\\"[" } ] }`)
+	for limit := 0; limit < 5; limit++ {
+		j, err := UnmarshalLimited(doc, limit)
+		if limit < 3 {
+			if err != ErrTooDeep {
+				t.Fatal("TestUnmarshalLimited.UnmarshalLimited failed", limit, err)
+			}
+			continue
+		}
+		if err != nil || j.MaxDepth() != 3 {
+			t.Fatal("TestUnmarshalLimited.UnmarshalLimited failed", limit, err)
+		}
+	}
+
+	if _, err := UnmarshalLimited([]byte(`42`), 0); err != nil {
+		t.Fatal("TestUnmarshalLimited.UnmarshalLimited failed", err)
+	}
+	if _, err := UnmarshalLimited([]byte(`{ "a": `), 10); err == nil {
+		t.Fatal("TestUnmarshalLimited.UnmarshalLimited failed")
+	}
+}
+
+func TestSetMany(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": { "b": 1 }, "list": [ 1, 2 ], "s": "x" }`))
+	if err != nil {
+		t.Fatal("TestSetMany failed", err)
+	}
+
+	err = j.SetMany(map[string]interface{}{
+		"a.b":     2,
+		"a.c":     3,
+		"list[5]": 6,
+		"z":       true,
+	})
+	perr, ok := err.(*PathError)
+	if !ok || perr.Path != "list[5]" || !errors.Is(err, ErrOutOfRange) {
+		t.Fatal("TestSetMany.SetMany failed", err)
+	}
+	err = j.SetMany(map[string]interface{}{"a.b": 2, "s.x": 1, "a..b": 1})
+	if perr, ok := err.(*PathError); !ok || perr.Path != "a..b" || perr.Err != ErrInvalidPath {
+		t.Fatal("TestSetMany.SetMany failed", err)
+	}
+	if s := j.String(); s != `{"a":{"b":1},"list":[1,2],"s":"x"}` {
+		t.Fatal("TestSetMany.SetMany failed", s)
+	}
+
+	if err := j.SetMany(map[string]interface{}{"a.b": 2, "a.c": 3, "list[1]": 4, "x.y[0]": "z"}); err != nil {
+		t.Fatal("TestSetMany.SetMany failed", err)
+	}
+	if s := j.String(); s != `{"a":{"b":2,"c":3},"list":[1,4],"s":"x","x":{"y":["z"]}}` {
+		t.Fatal("TestSetMany.SetMany failed", s)
+	}
+}
+
+func TestGetArray(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "rgb": [ 255, 128, 0 ], "short": [ 1 ], "long": [ 1, 2, 3, 4 ], "n": 1 }`))
+	if err != nil {
+		t.Fatal("TestGetArray failed", err)
+	}
+
+	var rgb [3]uint8
+	if err := j.Get("rgb", &rgb); err != nil || rgb != [3]uint8{255, 128, 0} {
+		t.Fatal("TestGetArray.Get failed", rgb, err)
+	}
+	if err := j.Get("short", &rgb); err != nil || rgb != [3]uint8{1, 0, 0} {
+		t.Fatal("TestGetArray.Get failed", rgb, err)
+	}
+	if err := j.Get("long", &rgb); err != ErrTruncate || rgb != [3]uint8{1, 2, 3} {
+		t.Fatal("TestGetArray.Get failed", rgb, err)
+	}
+	if err := j.Get("n", &rgb); err != ErrTypeMissmatch {
+		t.Fatal("TestGetArray.Get failed", err)
+	}
+}
+
+func TestRename(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "user": { "nmae": { "first": "Mirko" }, "age": 42 }, "list": [ 1 ] }`))
+	if err != nil {
+		t.Fatal("TestRename failed", err)
+	}
+
+	if err := j.Rename("user.nmae", "name"); err != nil {
+		t.Fatal("TestRename.Rename failed", err)
+	}
+	if s := j.MustGetString("user.name.first"); s != "Mirko" {
+		t.Fatal("TestRename.Rename failed", s)
+	}
+	if err := j.Rename("user.age", "name"); err != nil {
+		t.Fatal("TestRename.Rename failed", err)
+	}
+	if err := j.Rename("user.name", "name"); err != nil {
+		t.Fatal("TestRename.Rename failed", err)
+	}
+	if s := j.String(); s != `{"list":[1],"user":{"name":42}}` {
+		t.Fatal("TestRename.Rename failed", s)
+	}
+
+	if err := j.Rename("user.age", "x"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestRename.Rename failed", err)
+	}
+	if err := j.Rename("list[0]", "x"); err != ErrTypeMissmatch {
+		t.Fatal("TestRename.Rename failed", err)
+	}
+	if err := j.Rename("", "x"); err != ErrInvalidPath {
+		t.Fatal("TestRename.Rename failed", err)
+	}
+}
+
+func TestStack(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "queue": [ 2 ], "n": 1 }`))
+	if err != nil {
+		t.Fatal("TestStack failed", err)
+	}
+
+	if err := j.Push("queue", 3); err != nil {
+		t.Fatal("TestStack.Push failed", err)
+	}
+	if err := j.Unshift("queue", map[string]int{"v": 1}); err != nil {
+		t.Fatal("TestStack.Unshift failed", err)
+	}
+	if s := j.String(); s != `{"n":1,"queue":[{"v":1},2,3]}` {
+		t.Fatal("TestStack failed", s)
+	}
+
+	v, err := j.Pop("queue")
+	if err != nil || v.String() != "3" {
+		t.Fatal("TestStack.Pop failed", v, err)
+	}
+	v, err = j.Shift("queue")
+	if err != nil || v.MustGetInt("v") != 1 {
+		t.Fatal("TestStack.Shift failed", v, err)
+	}
+	if v, err = j.Shift("queue"); err != nil || v.String() != "2" {
+		t.Fatal("TestStack.Shift failed", v, err)
+	}
+	if _, err := j.Pop("queue"); err != ErrOutOfRange {
+		t.Fatal("TestStack.Pop failed", err)
+	}
+	if _, err := j.Shift("queue"); err != ErrOutOfRange {
+		t.Fatal("TestStack.Shift failed", err)
+	}
+	if s := j.String(); s != `{"n":1,"queue":[]}` {
+		t.Fatal("TestStack failed", s)
+	}
+
+	if err := j.Push("n", 1); err != ErrTypeMissmatch {
+		t.Fatal("TestStack.Push failed", err)
+	}
+	if _, err := j.Pop("n"); err != ErrTypeMissmatch {
+		t.Fatal("TestStack.Pop failed", err)
+	}
+}
+
+func TestGetLenient(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "id": "42", "pi": "3.14", "bad": "abc", "n": 7, "s": "x", "list": [ "1", 2 ] }`))
+	if err != nil {
+		t.Fatal("TestGetLenient failed", err)
+	}
+
+	var i int
+	if err := j.GetLenient("id", &i); err != nil || i != 42 {
+		t.Fatal("TestGetLenient.GetLenient failed", i, err)
+	}
+	if err := j.Get("id", &i); err != ErrInvalidOut {
+		t.Fatal("TestGetLenient.Get failed", err)
+	}
+	var f float64
+	if err := j.GetLenient("pi", &f); err != nil || f != 3.14 {
+		t.Fatal("TestGetLenient.GetLenient failed", f, err)
+	}
+	if err := j.GetLenient("pi", &i); err != ErrTruncate {
+		t.Fatal("TestGetLenient.GetLenient failed", err)
+	}
+	if err := j.GetLenient("bad", &i); err == nil {
+		t.Fatal("TestGetLenient.GetLenient failed", i)
+	} else if _, ok := err.(*strconv.NumError); !ok {
+		t.Fatal("TestGetLenient.GetLenient failed", err)
+	}
+
+	var list []uint8
+	if err := j.GetLenient("list", &list); err != nil || fmt.Sprint(list) != "[1 2]" {
+		t.Fatal("TestGetLenient.GetLenient failed", list, err)
+	}
+	var s string
+	if err := j.GetLenient("s", &s); err != nil || s != "x" {
+		t.Fatal("TestGetLenient.GetLenient failed", s, err)
+	}
+}
+
+func TestClear(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": { "list": [ 1, 2 ], "obj": { "x": 1 }, "s": "x" } }`))
+	if err != nil {
+		t.Fatal("TestClear failed", err)
+	}
+
+	if err := j.Clear("a.list"); err != nil {
+		t.Fatal("TestClear.Clear failed", err)
+	}
+	if err := j.Clear("a.obj"); err != nil {
+		t.Fatal("TestClear.Clear failed", err)
+	}
+	if s := j.String(); s != `{"a":{"list":[],"obj":{},"s":"x"}}` {
+		t.Fatal("TestClear.Clear failed", s)
+	}
+	if err := j.Clear("a.s"); err != ErrTypeMissmatch {
+		t.Fatal("TestClear.Clear failed", err)
+	}
+	if err := j.Clear(""); err != nil || j.String() != "{}" {
+		t.Fatal("TestClear.Clear failed", j, err)
+	}
+}
+
+func TestGetStringSlice(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "tags": [ "a", "b" ], "empty": [], "mixed": [ "a", 1 ], "s": "a" }`))
+	if err != nil {
+		t.Fatal("TestGetStringSlice failed", err)
+	}
+
+	if s, err := j.GetStringSlice("tags"); err != nil || fmt.Sprint(s) != "[a b]" {
+		t.Fatal("TestGetStringSlice.GetStringSlice failed", s, err)
+	}
+	if s, err := j.GetStringSlice("empty"); err != nil || s == nil || len(s) != 0 {
+		t.Fatal("TestGetStringSlice.GetStringSlice failed", s, err)
+	}
+	if _, err := j.GetStringSlice("mixed"); err != ErrTypeMissmatch {
+		t.Fatal("TestGetStringSlice.GetStringSlice failed", err)
+	}
+	if _, err := j.GetStringSlice("s"); err != ErrTypeMissmatch {
+		t.Fatal("TestGetStringSlice.GetStringSlice failed", err)
+	}
+	if _, err := j.GetStringSlice("x"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestGetStringSlice.GetStringSlice failed", err)
+	}
+}
+
+func TestGetIntSlice(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "ids": [ 1, -2, 3e3 ], "frac": [ 1, 2.5 ], "mixed": [ 1, "2" ], "n": 1 }`))
+	if err != nil {
+		t.Fatal("TestGetIntSlice failed", err)
+	}
+
+	if ids, err := j.GetIntSlice("ids"); err != nil || fmt.Sprint(ids) != "[1 -2 3000]" {
+		t.Fatal("TestGetIntSlice.GetIntSlice failed", ids, err)
+	}
+	if _, err := j.GetIntSlice("frac"); err != ErrTruncate {
+		t.Fatal("TestGetIntSlice.GetIntSlice failed", err)
+	}
+	if _, err := j.GetIntSlice("mixed"); err != ErrTypeMissmatch {
+		t.Fatal("TestGetIntSlice.GetIntSlice failed", err)
+	}
+	if _, err := j.GetIntSlice("n"); err != ErrTypeMissmatch {
+		t.Fatal("TestGetIntSlice.GetIntSlice failed", err)
+	}
+}
+
+func TestInterface(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": { "b": [ 1, "x", true, null ] } }`))
+	if err != nil {
+		t.Fatal("TestInterface failed", err)
+	}
+
+	v, err := j.Interface("a.b")
+	if err != nil {
+		t.Fatal("TestInterface.Interface failed", err)
+	}
+	slc, ok := v.([]interface{})
+	if !ok || len(slc) != 4 || slc[0] != 1.0 || slc[1] != "x" || slc[2] != true || slc[3] != nil {
+		t.Fatal("TestInterface.Interface failed", v)
+	}
+	if v, err = j.Interface(""); err != nil {
+		t.Fatal("TestInterface.Interface failed", err)
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		t.Fatal("TestInterface.Interface failed", v)
+	}
+	if _, err := j.Interface("a.c"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestInterface.Interface failed", err)
+	}
+	if _, err := j.Interface("a..c"); err != ErrInvalidPath {
+		t.Fatal("TestInterface.Interface failed", err)
+	}
+}
+
+func TestExportOptions(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "z": "<b>&</b>", "a": [ 1 ] }`))
+	if err != nil {
+		t.Fatal("TestExportOptions failed", err)
+	}
+
+	tests := []struct {
+		opts ExportOptions
+		want string
+	}{
+		{ExportOptions{}, `{"a":[1],"z":"<b>&</b>"}`},
+		{ExportOptions{EscapeHTML: true}, `{"a":[1],"z":"\u003cb\u003e\u0026\u003c/b\u003e"}`},
+		{ExportOptions{Indent: "  "}, "{\n  \"a\": [\n    1\n  ],\n  \"z\": \"<b>&</b>\"\n}"},
+	}
+	for _, test := range tests {
+		b, err := j.ExportOptions(test.opts)
+		if err != nil || string(b) != test.want {
+			t.Fatal("TestExportOptions.ExportOptions failed", test.opts, string(b), err)
+		}
+	}
+
+	b, err := j.Export("")
+	if err != nil || string(b) != tests[1].want {
+		t.Fatal("TestExportOptions.Export failed", string(b), err)
+	}
+}
+
+func TestExportRaw(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "html": "<b>bold</b> & more" }`))
+	if err != nil {
+		t.Fatal("TestExportRaw failed", err)
+	}
+
+	b, err := j.ExportRaw("")
+	if err != nil || string(b) != `{"html":"<b>bold</b> & more"}` {
+		t.Fatal("TestExportRaw.ExportRaw failed", string(b), err)
+	}
+	b, err = j.ExportRaw("\t")
+	if err != nil || string(b) != "{\n\t\"html\": \"<b>bold</b> & more\"\n}" {
+		t.Fatal("TestExportRaw.ExportRaw failed", string(b), err)
+	}
+}
+
+func TestDeleteWildcard(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "items": [ { "id": 1, "secret": "a" }, { "id": 2 }, { "id": 3, "secret": "c", "tags": [ 1, 2 ] } ] }`))
+	if err != nil {
+		t.Fatal("TestDeleteWildcard failed", err)
+	}
+
+	if err := j.Delete("items[*].secret"); err != nil {
+		t.Fatal("TestDeleteWildcard.Delete failed", err)
+	}
+	if s := j.String(); s != `{"items":[{"id":1},{"id":2},{"id":3,"tags":[1,2]}]}` {
+		t.Fatal("TestDeleteWildcard.Delete failed", s)
+	}
+	if err := j.Delete("items[*].tags[*]"); err != nil {
+		t.Fatal("TestDeleteWildcard.Delete failed", err)
+	}
+	if err := j.Delete("items[*]"); err != nil {
+		t.Fatal("TestDeleteWildcard.Delete failed", err)
+	}
+	if s := j.String(); s != `{"items":[]}` {
+		t.Fatal("TestDeleteWildcard.Delete failed", s)
+	}
+	if err := j.Delete("other[*].x"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestDeleteWildcard.Delete failed", err)
+	}
+
+	if j, err = Unmarshal([]byte(`{ "rows": [ [ 1, 2, 3 ], [ 4 ], [ 5, 6, 7 ] ] }`)); err != nil {
+		t.Fatal("TestDeleteWildcard failed", err)
+	}
+	if err := j.Delete("rows[*][2]"); err != nil {
+		t.Fatal("TestDeleteWildcard.Delete failed", err)
+	}
+	if s := j.String(); s != `{"rows":[[1,2],[4],[5,6]]}` {
+		t.Fatal("TestDeleteWildcard.Delete failed", s)
+	}
+	if j, err = Unmarshal([]byte(`{ "a": [ { "b": [ 0, [ 1, 2 ] ] }, { "b": [ 0 ] } ] }`)); err != nil {
+		t.Fatal("TestDeleteWildcard failed", err)
+	}
+	if err := j.Delete("a[*].b[1][*]"); err != nil {
+		t.Fatal("TestDeleteWildcard.Delete failed", err)
+	}
+	if s := j.String(); s != `{"a":[{"b":[0,[]]},{"b":[0]}]}` {
+		t.Fatal("TestDeleteWildcard.Delete failed", s)
+	}
+}
+
+func TestGetStrict(t *testing.T) {
+
+	type Base struct {
+		ID int `json:"id"`
+	}
+	type Item struct {
+		Base
+		Name string `json:"name"`
+		Sum  int    `jsonobj:"sum:values[*]"`
+	}
+	type Doc struct {
+		Items []Item `json:"items"`
+		Total int    `json:"total"`
+	}
+
+	j, err := Unmarshal([]byte(`{ "items": [ { "id": 1, "name": "a", "values": [ 1 ] }, { "id": 2, "nmae": "b" } ], "totl": 3 }`))
+	if err != nil {
+		t.Fatal("TestGetStrict failed", err)
+	}
+
+	doc := Doc{}
+	unknown, err := j.GetStrict("", &doc)
+	if err != nil {
+		t.Fatal("TestGetStrict.GetStrict failed", err)
+	}
+	sort.Strings(unknown)
+	if fmt.Sprint(unknown) != "[nmae totl values]" {
+		t.Fatal("TestGetStrict.GetStrict failed", unknown)
+	}
+	if len(doc.Items) != 2 || doc.Items[1].ID != 2 || doc.Items[0].Sum != 1 {
+		t.Fatal("TestGetStrict.GetStrict failed", doc)
+	}
+
+	item := Item{}
+	unknown, err = j.GetStrict("items[0]", &item)
+	if err != nil || unknown == nil || len(unknown) != 1 {
+		t.Fatal("TestGetStrict.GetStrict failed", unknown, err)
+	}
+	var id int
+	if unknown, err = j.GetStrict("items[0].id", &id); err != nil || unknown == nil || len(unknown) != 0 {
+		t.Fatal("TestGetStrict.GetStrict failed", unknown, err)
+	}
+}
+
+func TestGetDisallowUnknown(t *testing.T) {
+
+	type Limits struct {
+		Max int `json:"max"`
+	}
+	type Config struct {
+		Name   string  `json:"name"`
+		Limits *Limits `json:"limits"`
+	}
+
+	j, err := Unmarshal([]byte(`{ "name": "a", "limits": { "max": 9 } }`))
+	if err != nil {
+		t.Fatal("TestGetDisallowUnknown failed", err)
+	}
+	cfg := Config{}
+	if err := j.GetDisallowUnknown("", &cfg); err != nil || cfg.Limits == nil || cfg.Limits.Max != 9 {
+		t.Fatal("TestGetDisallowUnknown.GetDisallowUnknown failed", cfg, err)
+	}
+
+	j, err = Unmarshal([]byte(`{ "name": "a", "limits": { "max": 9, "min": 1 } }`))
+	if err != nil {
+		t.Fatal("TestGetDisallowUnknown failed", err)
+	}
+	if err := j.Get("", &cfg); err != nil {
+		t.Fatal("TestGetDisallowUnknown.Get failed", err)
+	}
+	err = j.GetDisallowUnknown("", &cfg)
+	if !errors.Is(err, ErrUnknownField) {
+		t.Fatal("TestGetDisallowUnknown.GetDisallowUnknown failed", err)
+	}
+	if ferr, ok := err.(*UnknownFieldError); !ok || ferr.Key != "min" || ferr.Error() != `unknown field: "min"` {
+		t.Fatal("TestGetDisallowUnknown.GetDisallowUnknown failed", err)
+	}
+}
+
+func TestSetOmitEmpty(t *testing.T) {
+
+	empty := map[string]interface{}{
+		"nil":      nil,
+		"string":   "",
+		"int":      0,
+		"uint":     uint8(0),
+		"float":    0.0,
+		"bool":     false,
+		"slice":    []int{},
+		"nilslice": []string(nil),
+		"map":      map[string]int{},
+		"pointer":  (*int)(nil),
+		"array":    [0]int{},
+	}
+	for key, v := range empty {
+		j, err := Unmarshal([]byte(`{ "a": { "` + key + `": 1 } }`))
+		if err != nil {
+			t.Fatal("TestSetOmitEmpty failed", err)
+		}
+		if err := j.SetOmitEmpty("a."+key, v); err != nil {
+			t.Fatal("TestSetOmitEmpty.SetOmitEmpty failed", key, err)
+		}
+		if s := j.String(); s != `{"a":{}}` {
+			t.Fatal("TestSetOmitEmpty.SetOmitEmpty failed", key, s)
+		}
+		if err := j.SetOmitEmpty("a."+key, v); err != nil {
+			t.Fatal("TestSetOmitEmpty.SetOmitEmpty failed", key, err)
+		}
+	}
+
+	j := NewObject()
+	if err := j.SetOmitEmpty("a.b", "x"); err != nil {
+		t.Fatal("TestSetOmitEmpty.SetOmitEmpty failed", err)
+	}
+	if err := j.SetOmitEmpty("a.c", struct{}{}); err != nil {
+		t.Fatal("TestSetOmitEmpty.SetOmitEmpty failed", err)
+	}
+	if s := j.String(); s != `{"a":{"b":"x","c":{}}}` {
+		t.Fatal("TestSetOmitEmpty.SetOmitEmpty failed", s)
+	}
+}
+
+func TestFindKeys(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "id": 1, "user": { "id": 2, "roles": [ { "id": 3 }, { "name": "x" }, [ { "id": 4 } ] ] }, "ids": [ "id" ] }`))
+	if err != nil {
+		t.Fatal("TestFindKeys failed", err)
+	}
+
+	paths, err := j.FindKeys("id")
+	if err != nil {
+		t.Fatal("TestFindKeys.FindKeys failed", err)
+	}
+	if fmt.Sprint(paths) != "[id user.id user.roles[0].id user.roles[2][0].id]" {
+		t.Fatal("TestFindKeys.FindKeys failed", paths)
+	}
+	if paths, err = j.FindKeys("password"); err != nil || paths == nil || len(paths) != 0 {
+		t.Fatal("TestFindKeys.FindKeys failed", paths, err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`[ { "user": "a", "password": "secret" }, { "user": "b" }, { "user": "c", "password": { "hash": "x" } } ]`))
+	if err != nil {
+		t.Fatal("TestRedact failed", err)
+	}
+
+	if err := j.Redact("[*].password", "[5].user", "[0].token", "x[*].y"); err != nil {
+		t.Fatal("TestRedact.Redact failed", err)
+	}
+	if s := j.String(); s != `[{"password":"***","user":"a"},{"user":"b"},{"password":"***","user":"c"}]` {
+		t.Fatal("TestRedact.Redact failed", s)
+	}
+	if err := j.RedactWith("<hidden>", "[1].user"); err != nil {
+		t.Fatal("TestRedact.RedactWith failed", err)
+	}
+	if s := j.MustGetString("[1].user"); s != "<hidden>" {
+		t.Fatal("TestRedact.RedactWith failed", s)
+	}
+	if err := j.Redact("[0].user", "[0]..user"); err != ErrInvalidPath {
+		t.Fatal("TestRedact.Redact failed", err)
+	}
+	if s := j.MustGetString("[0].user"); s != "a" {
+		t.Fatal("TestRedact.Redact failed", s)
+	}
+}
+
+func TestGetMapInterface(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "user": { "name": "Mirko", "address": { "city": "Zagreb" }, "tags": [ "a" ] } }`))
+	if err != nil {
+		t.Fatal("TestGetMapInterface failed", err)
+	}
+
+	m := map[string]interface{}{}
+	if err := j.Get("user", &m); err != nil {
+		t.Fatal("TestGetMapInterface.Get failed", err)
+	}
+	if m["name"] != "Mirko" || len(m) != 3 {
+		t.Fatal("TestGetMapInterface.Get failed", m)
+	}
+	address, ok := m["address"].(map[string]interface{})
+	if !ok || address["city"] != "Zagreb" {
+		t.Fatal("TestGetMapInterface.Get failed", m)
+	}
+
+	// The top level map is a copy, nested Objects are shared.
+	m["name"] = "Mirjana"
+	address["city"] = "Split"
+	if s := j.MustGetString("user.name"); s != "Mirko" {
+		t.Fatal("TestGetMapInterface.Get failed", s)
+	}
+	if s := j.MustGetString("user.address.city"); s != "Split" {
+		t.Fatal("TestGetMapInterface.Get failed", s)
+	}
+}
+
+func TestTest(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "n": 42, "s": "x", "o": { "a": [ 1, "b" ] }, "l": [ true, null ] }`))
+	if err != nil {
+		t.Fatal("TestTest failed", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected interface{}
+		want     bool
+	}{
+		{"n", 42, true},
+		{"n", uint8(42), true},
+		{"n", "42", false},
+		{"s", "x", true},
+		{"o", map[string]interface{}{"a": []interface{}{1, "b"}}, true},
+		{"o", struct {
+			A []interface{} `json:"a"`
+		}{[]interface{}{1, "b"}}, true},
+		{"o", map[string]interface{}{"a": []interface{}{1}}, false},
+		{"l", []interface{}{true, nil}, true},
+		{"l", []bool{true}, false},
+		{"l[1]", nil, true},
+	}
+	for _, test := range tests {
+		ok, err := j.Test(test.path, test.expected)
+		if err != nil || ok != test.want {
+			t.Fatal("TestTest.Test failed", test.path, test.expected, ok, err)
+		}
+	}
+
+	if _, err := j.Test("x", 1); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestTest.Test failed", err)
+	}
+}
+
+func TestGetPositional(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "rows": [ [ "AAPL", 189.5, 1200 ], [ "MSFT" ] ], "s": "x" }`))
+	if err != nil {
+		t.Fatal("TestGetPositional failed", err)
+	}
+
+	type Row struct {
+		Symbol string
+		note   string
+		Skip   bool `json:"-"`
+		Price  float64
+		Volume int
+	}
+
+	row := Row{}
+	if err := j.GetPositional("rows[0]", &row); err != nil {
+		t.Fatal("TestGetPositional.GetPositional failed", err)
+	}
+	if row != (Row{Symbol: "AAPL", Price: 189.5, Volume: 1200}) {
+		t.Fatal("TestGetPositional.GetPositional failed", row)
+	}
+	if err := j.GetPositional("rows[1]", &row); err != nil {
+		t.Fatal("TestGetPositional.GetPositional failed", err)
+	}
+	if row != (Row{Symbol: "MSFT", Price: 189.5, Volume: 1200}) {
+		t.Fatal("TestGetPositional.GetPositional failed", row)
+	}
+
+	if err := j.GetPositional("s", &row); err != ErrTypeMissmatch {
+		t.Fatal("TestGetPositional.GetPositional failed", err)
+	}
+	var slc []string
+	if err := j.GetPositional("rows[1]", &slc); err != ErrInvalidOut {
+		t.Fatal("TestGetPositional.GetPositional failed", err)
+	}
+}
+
+// recorder is a Visitor recording visited elements.
+type recorder struct {
+	calls []string
+	stop  string
+}
+
+func (r *recorder) record(call string) error {
+
+	r.calls = append(r.calls, call)
+	if call == r.stop {
+		return errors.New("stop")
+	}
+	return nil
+}
+
+func (r *recorder) OnObject(path string) error           { return r.record("object " + path) }
+func (r *recorder) OnArray(path string) error            { return r.record("array " + path) }
+func (r *recorder) OnString(path string, v string) error { return r.record("string " + path + "=" + v) }
+func (r *recorder) OnNumber(path string, v float64) error {
+	return r.record(fmt.Sprint("number ", path, "=", v))
+}
+func (r *recorder) OnBool(path string, v bool) error {
+	return r.record(fmt.Sprint("bool ", path, "=", v))
+}
+func (r *recorder) OnNull(path string) error { return r.record("null " + path) }
+
+func TestWalkTyped(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "s": "x", "a": [ 1.5, true, null, {} ], "o": { "b": false } }`))
+	if err != nil {
+		t.Fatal("TestWalkTyped failed", err)
+	}
+
+	r := &recorder{}
+	if err := j.WalkTyped(r); err != nil {
+		t.Fatal("TestWalkTyped.WalkTyped failed", err)
+	}
+	want := []string{
+		"object ",
+		"array a",
+		"number a[0]=1.5",
+		"bool a[1]=true",
+		"null a[2]",
+		"object a[3]",
+		"object o",
+		"bool o.b=false",
+		"string s=x",
+	}
+	if strings.Join(r.calls, "|") != strings.Join(want, "|") {
+		t.Fatal("TestWalkTyped.WalkTyped failed", r.calls)
+	}
+
+	r = &recorder{stop: "null a[2]"}
+	if err := j.WalkTyped(r); err == nil || err.Error() != "stop" || len(r.calls) != 5 {
+		t.Fatal("TestWalkTyped.WalkTyped failed", r.calls, err)
+	}
+}
+
+func TestSetTypeTransitions(t *testing.T) {
+
+	values := map[string]interface{}{
+		"number": 1,
+		"string": "x",
+		"bool":   true,
+		"null":   nil,
+		"object": map[string]interface{}{"k": 1},
+		"array":  []interface{}{1, "x"},
+	}
+	encoded := map[string]string{
+		"number": `1`,
+		"string": `"x"`,
+		"bool":   `true`,
+		"null":   `null`,
+		"object": `{"k":1}`,
+		"array":  `[1,"x"]`,
+	}
+
+	for from := range values {
+		for to, v := range values {
+			if to == "null" {
+				// Set rejects a nil in.
+				continue
+			}
+			j, err := Unmarshal([]byte(`{ "a": { "key": ` + encoded[from] + ` }, "l": [ ` + encoded[from] + ` ] }`))
+			if err != nil {
+				t.Fatal("TestSetTypeTransitions failed", err)
+			}
+			if err := j.Set("a.key", v); err != nil {
+				t.Fatal("TestSetTypeTransitions.Set failed", from, to, err)
+			}
+			if err := j.Set("l[0]", v); err != nil {
+				t.Fatal("TestSetTypeTransitions.Set failed", from, to, err)
+			}
+			want := `{"a":{"key":` + encoded[to] + `},"l":[` + encoded[to] + `]}`
+			if s := j.String(); s != want {
+				t.Fatal("TestSetTypeTransitions.Set failed", from, to, s)
+			}
+		}
+	}
+
+	// Descending through a former scalar works once it is replaced.
+	j, err := Unmarshal([]byte(`{ "a": 1 }`))
+	if err != nil {
+		t.Fatal("TestSetTypeTransitions failed", err)
+	}
+	if err := j.Set("a.b", 2); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestSetTypeTransitions.Set failed", err)
+	}
+	if err := j.Set("a", map[string]interface{}{}); err != nil {
+		t.Fatal("TestSetTypeTransitions.Set failed", err)
+	}
+	if err := j.Set("a.b[1]", 2); err != nil {
+		t.Fatal("TestSetTypeTransitions.Set failed", err)
+	}
+	if s := j.String(); s != `{"a":{"b":[null,2]}}` {
+		t.Fatal("TestSetTypeTransitions.Set failed", s)
+	}
+}
+
+func TestGetDuration(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "timeout": "1h30m", "ns": 1500, "bad": "5 minutes", "frac": 1.5, "b": true }`))
+	if err != nil {
+		t.Fatal("TestGetDuration failed", err)
+	}
+
+	if d, err := j.GetDuration("timeout"); err != nil || d != 90*time.Minute {
+		t.Fatal("TestGetDuration.GetDuration failed", d, err)
+	}
+	if d, err := j.GetDuration("ns"); err != nil || d != 1500*time.Nanosecond {
+		t.Fatal("TestGetDuration.GetDuration failed", d, err)
+	}
+	if _, err := j.GetDuration("bad"); err == nil {
+		t.Fatal("TestGetDuration.GetDuration failed")
+	}
+	if _, err := j.GetDuration("frac"); err != ErrTruncate {
+		t.Fatal("TestGetDuration.GetDuration failed", err)
+	}
+	if _, err := j.GetDuration("b"); err != ErrTypeMissmatch {
+		t.Fatal("TestGetDuration.GetDuration failed", err)
+	}
+}
+
+func TestRequire(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "o": {}, "a": [], "s": "x", "n": 1, "b": false, "z": null }`))
+	if err != nil {
+		t.Fatal("TestRequire failed", err)
+	}
+
+	kinds := map[string]string{"o": "object", "a": "array", "s": "string", "n": "number", "b": "bool", "z": "null"}
+	for path, kind := range kinds {
+		for _, other := range kinds {
+			err := j.Require(path, other)
+			if other == kind {
+				if err != nil {
+					t.Fatal("TestRequire.Require failed", path, other, err)
+				}
+				continue
+			}
+			kerr, ok := err.(*KindError)
+			if !ok || !errors.Is(err, ErrTypeMissmatch) || kerr.Path != path || kerr.Expected != other || kerr.Actual != kind {
+				t.Fatal("TestRequire.Require failed", path, other, err)
+			}
+		}
+	}
+
+	if err := j.Require("n", "string"); err.Error() != `expected string but found number at "n"` {
+		t.Fatal("TestRequire.Require failed", err)
+	}
+	if err := j.Require("o.x", "string"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestRequire.Require failed", err)
+	}
+	if err := j.Require("o", "map"); err != ErrInvalidIn {
+		t.Fatal("TestRequire.Require failed", err)
+	}
+}
+
+func TestCount(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "o": { "a": 1, "b": 2 }, "a": [ 1, 2, 3 ], "e": [], "s": "xyz", "n": 1, "b": true, "z": null }`))
+	if err != nil {
+		t.Fatal("TestCount failed", err)
+	}
+
+	tests := map[string]int{"": 7, "o": 2, "a": 3, "e": 0, "s": 0, "n": 0, "b": 0, "z": 0}
+	for path, want := range tests {
+		if n, err := j.Count(path); err != nil || n != want {
+			t.Fatal("TestCount.Count failed", path, n, err)
+		}
+	}
+	if n, err := j.Count("x"); !errors.Is(err, ErrNotFound) || n != -1 {
+		t.Fatal("TestCount.Count failed", n, err)
+	}
+}
+
+func TestGetNested(t *testing.T) {
+
+	const doc = `{
+		"name": "Acme",
+		"departments": [
+			{
+				"name": "Engineering",
+				"status": "active",
+				"budget": 1.5e6,
+				"teams": [
+					{
+						"name": "Core",
+						"members": [
+							{ "name": "Mirko", "roles": [ "lead", "dev" ], "skills": { "go": 5 } },
+							{ "name": "Mirjana", "roles": [ "dev" ] }
+						]
+					},
+					{ "name": "Empty", "members": [] }
+				]
+			},
+			{ "name": "Sales", "status": "closed", "teams": null }
+		],
+		"matrix": [ [ { "v": 1 } ], [ { "v": 2 }, { "v": 3 } ] ]
+	}`
+
+	type Status string
+	type Member struct {
+		Name   string         `json:"name"`
+		Roles  []string       `json:"roles"`
+		Skills map[string]int `json:"skills"`
+	}
+	type Team struct {
+		Name    string    `json:"name"`
+		Members []*Member `json:"members"`
+	}
+	type Department struct {
+		Name   string  `json:"name"`
+		Status Status  `json:"status"`
+		Budget float32 `json:"budget"`
+		Teams  []Team  `json:"teams"`
+	}
+	type Company struct {
+		Name        string       `json:"name"`
+		Departments []Department `json:"departments"`
+		Matrix      [][]struct {
+			V int `json:"v"`
+		} `json:"matrix"`
+	}
+
+	j, err := Unmarshal([]byte(doc))
+	if err != nil {
+		t.Fatal("TestGetNested failed", err)
+	}
+
+	c := Company{}
+	if err := j.Get("", &c); err != nil {
+		t.Fatal("TestGetNested.Get failed", err)
+	}
+	if c.Name != "Acme" || len(c.Departments) != 2 {
+		t.Fatal("TestGetNested.Get failed", c)
+	}
+	eng := c.Departments[0]
+	if eng.Name != "Engineering" || eng.Status != "active" || eng.Budget != 1.5e6 || len(eng.Teams) != 2 {
+		t.Fatal("TestGetNested.Get failed", eng)
+	}
+	core := eng.Teams[0]
+	if core.Name != "Core" || len(core.Members) != 2 || eng.Teams[1].Members == nil || len(eng.Teams[1].Members) != 0 {
+		t.Fatal("TestGetNested.Get failed", eng.Teams)
+	}
+	m := core.Members[0]
+	if m.Name != "Mirko" || fmt.Sprint(m.Roles) != "[lead dev]" || m.Skills["go"] != 5 {
+		t.Fatal("TestGetNested.Get failed", m)
+	}
+	if core.Members[1].Name != "Mirjana" || core.Members[1].Skills != nil {
+		t.Fatal("TestGetNested.Get failed", core.Members[1])
+	}
+	if c.Departments[1].Status != "closed" || c.Departments[1].Teams != nil {
+		t.Fatal("TestGetNested.Get failed", c.Departments[1])
+	}
+	if len(c.Matrix) != 2 || len(c.Matrix[1]) != 2 || c.Matrix[1][1].V != 3 {
+		t.Fatal("TestGetNested.Get failed", c.Matrix)
+	}
+
+	members := []Member{}
+	if err := j.Get("departments[0].teams[0].members", &members); err != nil || len(members) != 2 {
+		t.Fatal("TestGetNested.Get failed", members, err)
+	}
+
+	type Flag bool
+	type Count uint16
+	type Ratio float64
+	out := struct {
+		B Flag  `json:"b"`
+		C Count `json:"c"`
+		R Ratio `json:"r"`
+	}{}
+	if j, err = Unmarshal([]byte(`{ "b": true, "c": 7, "r": 0.5 }`)); err != nil {
+		t.Fatal("TestGetNested failed", err)
+	}
+	if err := j.Get("", &out); err != nil || !out.B || out.C != 7 || out.R != 0.5 {
+		t.Fatal("TestGetNested.Get failed", out, err)
+	}
+}
+
+func TestSetIfAbsent(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": { "b": 1, "z": null }, "l": [ 1 ] }`))
+	if err != nil {
+		t.Fatal("TestSetIfAbsent failed", err)
+	}
+
+	tests := []struct {
+		path string
+		set  bool
+	}{
+		{"a.b", false},
+		{"a.z", false},
+		{"l[0]", false},
+		{"a.c", true},
+		{"x.y[1].z", true},
+		{"x.y[1].z", false},
+	}
+	for _, test := range tests {
+		set, err := j.SetIfAbsent(test.path, "new")
+		if err != nil || set != test.set {
+			t.Fatal("TestSetIfAbsent.SetIfAbsent failed", test.path, set, err)
+		}
+	}
+	if s := j.String(); s != `{"a":{"b":1,"c":"new","z":null},"l":[1],"x":{"y":[null,{"z":"new"}]}}` {
+		t.Fatal("TestSetIfAbsent.SetIfAbsent failed", s)
+	}
+
+	if set, err := j.SetIfAbsent("l[1]", 2); set || !errors.Is(err, ErrOutOfRange) {
+		t.Fatal("TestSetIfAbsent.SetIfAbsent failed", set, err)
+	}
+	if set, err := j.SetIfAbsent("a..b", 2); set || err != ErrInvalidPath {
+		t.Fatal("TestSetIfAbsent.SetIfAbsent failed", set, err)
+	}
+}
+
+func TestGetOrSet(t *testing.T) {
+
+	j := NewObject()
+
+	type Settings struct {
+		Retries int    `json:"retries"`
+		Mode    string `json:"mode"`
+	}
+	s := Settings{}
+	if err := j.GetOrSet("config.settings", Settings{3, "fast"}, &s); err != nil || s != (Settings{3, "fast"}) {
+		t.Fatal("TestGetOrSet.GetOrSet failed", s, err)
+	}
+	if err := j.Set("config.settings.retries", 5); err != nil {
+		t.Fatal("TestGetOrSet.Set failed", err)
+	}
+	if err := j.GetOrSet("config.settings", Settings{3, "fast"}, &s); err != nil || s != (Settings{5, "fast"}) {
+		t.Fatal("TestGetOrSet.GetOrSet failed", s, err)
+	}
+
+	var n int
+	if err := j.GetOrSet("config.settings.mode", 1, &n); err != ErrInvalidOut {
+		t.Fatal("TestGetOrSet.GetOrSet failed", err)
+	}
+	if err := j.GetOrSet("config.settings.mode.x", 1, &n); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestGetOrSet.GetOrSet failed", err)
+	}
+}
+
+func TestGetUnixTime(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "unix": 1527856200, "frac": 1527856200.25, "neg": -1.5, "rfc": "2018-06-01T12:30:00Z" }`))
+	if err != nil {
+		t.Fatal("TestGetUnixTime failed", err)
+	}
+
+	want := time.Date(2018, 6, 1, 12, 30, 0, 0, time.UTC)
+	var tm time.Time
+	if err := j.Get("unix", &tm); err != nil || !tm.Equal(want) || tm.Location() != time.UTC {
+		t.Fatal("TestGetUnixTime.Get failed", tm, err)
+	}
+	if err := j.Get("rfc", &tm); err != nil || !tm.Equal(want) {
+		t.Fatal("TestGetUnixTime.Get failed", tm, err)
+	}
+	if err := j.Get("frac", &tm); err != nil || !tm.Equal(want.Add(250*time.Millisecond)) {
+		t.Fatal("TestGetUnixTime.Get failed", tm, err)
+	}
+	if err := j.Get("neg", &tm); err != nil || !tm.Equal(time.Unix(-2, 5e8)) {
+		t.Fatal("TestGetUnixTime.Get failed", tm, err)
+	}
+}
+
+func TestSplice(t *testing.T) {
+
+	tests := []struct {
+		start, count int
+		items        []interface{}
+		want         string
+	}{
+		{1, 2, nil, "[0,3,4]"},
+		{2, 0, []interface{}{"a", "b"}, `[0,1,"a","b",2,3,4]`},
+		{1, 3, []interface{}{"x"}, `[0,"x",4]`},
+		{-2, 1, []interface{}{"x"}, `[0,1,2,"x",4]`},
+		{3, 10, nil, "[0,1,2]"},
+		{0, -1, []interface{}{"x"}, `["x",0,1,2,3,4]`},
+		{5, 1, []interface{}{5}, "[0,1,2,3,4,5]"},
+	}
+	for _, test := range tests {
+		j, err := Unmarshal([]byte(`{ "l": [ 0, 1, 2, 3, 4 ], "n": 1 }`))
+		if err != nil {
+			t.Fatal("TestSplice failed", err)
+		}
+		if err := j.Splice("l", test.start, test.count, test.items...); err != nil {
+			t.Fatal("TestSplice.Splice failed", test, err)
+		}
+		var l interface{}
+		if err := j.Get("l", &l); err != nil {
+			t.Fatal("TestSplice.Get failed", err)
+		}
+		if s := (&JSON{intf: l}).String(); s != test.want {
+			t.Fatal("TestSplice.Splice failed", test, s)
+		}
+	}
+
+	j, err := Unmarshal([]byte(`{ "l": [ 0 ], "n": 1 }`))
+	if err != nil {
+		t.Fatal("TestSplice failed", err)
+	}
+	if err := j.Splice("l", 2, 0); err != ErrOutOfRange {
+		t.Fatal("TestSplice.Splice failed", err)
+	}
+	if err := j.Splice("l", -2, 0); err != ErrOutOfRange {
+		t.Fatal("TestSplice.Splice failed", err)
+	}
+	if err := j.Splice("n", 0, 0); err != ErrTypeMissmatch {
+		t.Fatal("TestSplice.Splice failed", err)
+	}
+	if err := j.Splice("l", 0, 0, nil); err != ErrInvalidIn {
+		t.Fatal("TestSplice.Splice failed", err)
+	}
+}
+
+func TestStreamArray(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "users": [ { "name": "Mirko", "age": 42 }, { "name": "Mirjana", "age": 64 } ], "empty": [], "n": 1 }`))
+	if err != nil {
+		t.Fatal("TestStreamArray failed", err)
+	}
+
+	buf := bytes.Buffer{}
+	err = j.StreamArray("users", &buf, func(item *JSON) (interface{}, error) {
+		return map[string]interface{}{
+			"upper": strings.ToUpper(item.MustGetString("name")),
+			"adult": item.MustGetInt("age") >= 18,
+		}, nil
+	})
+	if err != nil || buf.String() != `[{"adult":true,"upper":"MIRKO"},{"adult":true,"upper":"MIRJANA"}]` {
+		t.Fatal("TestStreamArray.StreamArray failed", buf.String(), err)
+	}
+
+	identity := func(item *JSON) (interface{}, error) { return item.intf, nil }
+	buf.Reset()
+	if err := j.StreamArray("empty", &buf, identity); err != nil || buf.String() != "[]" {
+		t.Fatal("TestStreamArray.StreamArray failed", buf.String(), err)
+	}
+	if err := j.StreamArray("n", &buf, identity); err != ErrTypeMissmatch {
+		t.Fatal("TestStreamArray.StreamArray failed", err)
+	}
+	stop := errors.New("stop")
+	if err := j.StreamArray("users", &buf, func(*JSON) (interface{}, error) { return nil, stop }); err != stop {
+		t.Fatal("TestStreamArray.StreamArray failed", err)
+	}
+}
+
+func TestGetWithMatcher(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "user_id": 7, "display_name": "Mirko", "home_address": { "zip_code": "10000" }, "x": 1 }`))
+	if err != nil {
+		t.Fatal("TestGetWithMatcher failed", err)
+	}
+
+	type Address struct {
+		ZipCode string
+	}
+	type User struct {
+		UserID      int
+		DisplayName string
+		HomeAddress Address
+		Other       int `json:"x"`
+	}
+	snake := func(jsonKey, fieldName string) bool {
+		return strings.EqualFold(strings.Replace(jsonKey, "_", "", -1), fieldName)
+	}
+
+	u := User{}
+	if err := j.GetWithMatcher("", &u, snake); err != nil {
+		t.Fatal("TestGetWithMatcher.GetWithMatcher failed", err)
+	}
+	if u != (User{7, "Mirko", Address{"10000"}, 1}) {
+		t.Fatal("TestGetWithMatcher.GetWithMatcher failed", u)
+	}
+
+	u = User{}
+	if err := j.Get("", &u); err != nil || u != (User{Other: 1}) {
+		t.Fatal("TestGetWithMatcher.Get failed", u, err)
+	}
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+
+	clean := `{ "a": [ { "x": 1, "y": {} }, { "x": 2 }, [] ], "b": { "a": 1, "x": [ { "x": 1 } ] }, "x": null }`
+	j, err := UnmarshalStrict([]byte(clean))
+	if err != nil || j.MustGetInt("a[1].x") != 2 {
+		t.Fatal("TestUnmarshalStrict.UnmarshalStrict failed", err)
+	}
+
+	dups := map[string]string{
+		`{ "a": 1, "a": 2 }`:                                       `duplicate key: "a" in ""`,
+		`{ "a": [ {}, { "x": 1, "y": 2, "x": 3 } ] }`:              `duplicate key: "x" in "a[1]"`,
+		`[ { "b": { "c": [ 1 ], "d": {}, "d": 1 } } ]`:             `duplicate key: "d" in "[0].b"`,
+		`{ "a": { "a": 1 }, "b": [ [], [ { "k": 1, "k": 1 } ] ] }`: `duplicate key: "k" in "b[1][0]"`,
+	}
+	for doc, want := range dups {
+		_, err := UnmarshalStrict([]byte(doc))
+		if !errors.Is(err, ErrDuplicateKey) || err.Error() != want {
+			t.Fatal("TestUnmarshalStrict.UnmarshalStrict failed", doc, err)
+		}
+	}
+
+	if _, err := UnmarshalStrict([]byte(`{ "a": `)); err == nil {
+		t.Fatal("TestUnmarshalStrict.UnmarshalStrict failed")
+	}
+}
+
+func TestInlineTag(t *testing.T) {
+
+	type Base struct {
+		ID int `json:"id"`
+	}
+	type Item struct {
+		Base
+		Name  string                 `json:"name"`
+		Extra map[string]interface{} `jsonobj:",inline"`
+	}
+
+	j, err := Unmarshal([]byte(`{ "id": 1, "name": "a", "color": "red", "size": { "w": 2 } }`))
+	if err != nil {
+		t.Fatal("TestInlineTag failed", err)
+	}
+
+	item := Item{}
+	if err := j.Get("", &item); err != nil {
+		t.Fatal("TestInlineTag.Get failed", err)
+	}
+	if item.ID != 1 || item.Name != "a" || len(item.Extra) != 2 || item.Extra["color"] != "red" {
+		t.Fatal("TestInlineTag.Get failed", item)
+	}
+	if size, ok := item.Extra["size"].(map[string]interface{}); !ok || size["w"] != 2.0 {
+		t.Fatal("TestInlineTag.Get failed", item.Extra)
+	}
+
+	if err := j.GetDisallowUnknown("", &item); err != nil {
+		t.Fatal("TestInlineTag.GetDisallowUnknown failed", err)
+	}
+
+	item = Item{}
+	if err := j.Set("", map[string]interface{}{"id": 2, "name": "b"}); err != nil {
+		t.Fatal("TestInlineTag.Set failed", err)
+	}
+	if err := j.Get("", &item); err != nil || item.ID != 2 || item.Extra != nil {
+		t.Fatal("TestInlineTag.Get failed", item, err)
+	}
+}
+
+func TestGetInt64(t *testing.T) {
+
+	const doc = `{ "id": 1234567890123456789, "small": -42, "frac": 1.5, "s": "x" }`
+	const id = int64(1234567890123456789)
+
+	j, err := Unmarshal([]byte(doc))
+	if err != nil {
+		t.Fatal("TestGetInt64 failed", err)
+	}
+	if v, err := j.GetInt64("id"); err == nil && v == id {
+		t.Fatal("TestGetInt64.GetInt64 failed", v)
+	}
+
+	if j, err = UnmarshalUseNumber([]byte(doc)); err != nil {
+		t.Fatal("TestGetInt64 failed", err)
+	}
+	if v, err := j.GetInt64("id"); err != nil || v != id {
+		t.Fatal("TestGetInt64.GetInt64 failed", v, err)
+	}
+	if v, err := j.GetInt64("small"); err != nil || v != -42 {
+		t.Fatal("TestGetInt64.GetInt64 failed", v, err)
+	}
+	if _, err := j.GetInt64("frac"); err != ErrTruncate {
+		t.Fatal("TestGetInt64.GetInt64 failed", err)
+	}
+	if _, err := j.GetInt64("s"); err != ErrInvalidOut {
+		t.Fatal("TestGetInt64.GetInt64 failed", err)
+	}
+	var u uint64
+	if err := j.Get("id", &u); err != nil || u != uint64(id) {
+		t.Fatal("TestGetInt64.Get failed", u, err)
+	}
+	var i8 int8
+	if err := j.Get("id", &i8); err != ErrTruncate {
+		t.Fatal("TestGetInt64.Get failed", err)
+	}
+	var f float64
+	if err := j.Get("frac", &f); err != nil || f != 1.5 {
+		t.Fatal("TestGetInt64.Get failed", f, err)
+	}
+	var n interface{}
+	if err := j.Get("id", &n); err != nil || n != json.Number("1234567890123456789") {
+		t.Fatal("TestGetInt64.Get failed", n, err)
+	}
+	if b, err := j.Export(""); err != nil || !strings.Contains(string(b), `"id":1234567890123456789`) {
+		t.Fatal("TestGetInt64.Export failed", string(b), err)
+	}
+	if err := j.Require("id", "number"); err != nil {
+		t.Fatal("TestGetInt64.Require failed", err)
+	}
+
+	_, err = UnmarshalUseNumber([]byte(`{} {}`))
+	if _, want := Unmarshal([]byte(`{} {}`)); err == nil || err.Error() != want.Error() {
+		t.Fatal("TestGetInt64.UnmarshalUseNumber failed", err)
+	}
+}
+
+func TestUseNumberOutputs(t *testing.T) {
+
+	j, err := UnmarshalUseNumber([]byte(`{ "id": 9007199254740993, "f": 1.5 }`))
+	if err != nil {
+		t.Fatal("TestUseNumberOutputs failed", err)
+	}
+
+	var pi *int64
+	if err := j.Get("id", &pi); err != nil || pi == nil || *pi != 9007199254740993 {
+		t.Fatal("TestUseNumberOutputs.Get failed", pi, err)
+	}
+	var doc struct {
+		ID *int64 `json:"id"`
+	}
+	if err := j.Get("", &doc); err != nil || doc.ID == nil || *doc.ID != 9007199254740993 {
+		t.Fatal("TestUseNumberOutputs.Get failed", doc.ID, err)
+	}
+	b := new(big.Int)
+	if err := j.Get("id", b); err != nil || b.String() != "9007199254740993" {
+		t.Fatal("TestUseNumberOutputs.Get failed", b, err)
+	}
+	var n json.Number
+	if err := j.Get("id", &n); err != nil || n != "9007199254740993" {
+		t.Fatal("TestUseNumberOutputs.Get failed", n, err)
+	}
+	var pn *json.Number
+	if err := j.Get("f", &pn); err != nil || pn == nil || *pn != "1.5" {
+		t.Fatal("TestUseNumberOutputs.Get failed", pn, err)
+	}
+	var f float32
+	if err := j.Get("f", &f); err != nil || f != 1.5 {
+		t.Fatal("TestUseNumberOutputs.Get failed", f, err)
+	}
+	var bs []byte
+	if err := j.Get("id", &bs); err != ErrTypeMissmatch {
+		t.Fatal("TestUseNumberOutputs.Get failed", bs, err)
+	}
+}
+
+func TestUseNumberExact(t *testing.T) {
+
+	a, err := UnmarshalUseNumber([]byte(`{ "id": 1234567890123456789 }`))
+	if err != nil {
+		t.Fatal("TestUseNumberExact failed", err)
+	}
+	b, err := UnmarshalUseNumber([]byte(`{ "id": 1234567890123456788 }`))
+	if err != nil {
+		t.Fatal("TestUseNumberExact failed", err)
+	}
+
+	if c, err := a.Canonical(); err != nil || string(c) != `{"id":1234567890123456789}` {
+		t.Fatal("TestUseNumberExact.Canonical failed", string(c), err)
+	}
+	if a.Equal(b) {
+		t.Fatal("TestUseNumberExact.Equal failed")
+	}
+	if ok, err := a.Test("id", int64(1234567890123456788)); err != nil || ok {
+		t.Fatal("TestUseNumberExact.Test failed", ok, err)
+	}
+	if ok, err := a.Test("id", int64(1234567890123456789)); err != nil || !ok {
+		t.Fatal("TestUseNumberExact.Test failed", ok, err)
+	}
+	patch, err := a.Diff(b)
+	if err != nil || string(patch) != `[{"op":"replace","path":"/id","value":1234567890123456788}]` {
+		t.Fatal("TestUseNumberExact.Diff failed", string(patch), err)
+	}
+}
+
+func TestUseNumberValues(t *testing.T) {
+
+	const doc = `{ "a": 1, "l": [ 1, 2.0, 3e0 ], "d": 1000, "items": [ { "n": 2 }, { "n": 1 } ], "total": 0 }`
+
+	j, err := UnmarshalUseNumber([]byte(doc))
+	if err != nil {
+		t.Fatal("TestUseNumberValues failed", err)
+	}
+	f, err := Unmarshal([]byte(doc))
+	if err != nil {
+		t.Fatal("TestUseNumberValues failed", err)
+	}
+
+	if !j.Equal(f) || !f.Equal(j) {
+		t.Fatal("TestUseNumberValues.Equal failed")
+	}
+	if ok, err := j.Test("a", 1); err != nil || !ok {
+		t.Fatal("TestUseNumberValues.Test failed", ok, err)
+	}
+	if sel, err := j.Select("items", "n", 1); err != nil || len(sel) != 1 {
+		t.Fatal("TestUseNumberValues.Select failed", sel, err)
+	}
+	if err := j.ApplyPatch([]byte(`[ { "op": "test", "path": "/l/1", "value": 2 } ]`)); err != nil {
+		t.Fatal("TestUseNumberValues.ApplyPatch failed", err)
+	}
+	if st, err := j.Stats("l[*]"); err != nil || st.Sum != 6 {
+		t.Fatal("TestUseNumberValues.Stats failed", st, err)
+	}
+	var out struct {
+		Sum int `jsonobj:"sum:items[*].n"`
+	}
+	if err := j.Get("", &out); err != nil || out.Sum != 3 {
+		t.Fatal("TestUseNumberValues.Get failed", out, err)
+	}
+	if l, err := j.GetIntSlice("l"); err != nil || fmt.Sprint(l) != "[1 2 3]" {
+		t.Fatal("TestUseNumberValues.GetIntSlice failed", l, err)
+	}
+	if d, err := j.GetDuration("d"); err != nil || d != time.Microsecond {
+		t.Fatal("TestUseNumberValues.GetDuration failed", d, err)
+	}
+	a, err := j.Canonical()
+	if err != nil {
+		t.Fatal("TestUseNumberValues.Canonical failed", err)
+	}
+	if b, err := f.Canonical(); err != nil || string(a) != string(b) {
+		t.Fatal("TestUseNumberValues.Canonical failed", string(a), string(b), err)
+	}
+}
+
+func TestSwap(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "l": [ "a", "b", { "c": 1 }, "d" ], "n": 1 }`))
+	if err != nil {
+		t.Fatal("TestSwap failed", err)
+	}
+
+	if err := j.Swap("l", 0, 2); err != nil {
+		t.Fatal("TestSwap.Swap failed", err)
+	}
+	if err := j.Swap("l", 1, -1); err != nil {
+		t.Fatal("TestSwap.Swap failed", err)
+	}
+	if err := j.Swap("l", 3, 3); err != nil {
+		t.Fatal("TestSwap.Swap failed", err)
+	}
+	if s := j.String(); s != `{"l":[{"c":1},"d","a","b"],"n":1}` {
+		t.Fatal("TestSwap.Swap failed", s)
+	}
+
+	if err := j.Swap("l", 0, 4); err != ErrOutOfRange {
+		t.Fatal("TestSwap.Swap failed", err)
+	}
+	if err := j.Swap("l", -5, 0); err != ErrOutOfRange {
+		t.Fatal("TestSwap.Swap failed", err)
+	}
+	if err := j.Swap("n", 0, 0); err != ErrTypeMissmatch {
+		t.Fatal("TestSwap.Swap failed", err)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+
+	const doc = `[
+		{ "name": "Saturn", "moons": 62 },
+		{ "name": "Venus" },
+		{ "name": "Mars", "moons": 2 },
+		42,
+		{ "name": "Jupiter", "moons": "many" },
+		{ "name": "Earth", "moons": 1 },
+		{ "name": "Pluto", "moons": null }
+	]`
+
+	tests := []struct {
+		field     string
+		ascending bool
+		want      string
+	}{
+		{"moons", true, "Earth Mars Saturn Jupiter Venus 42 Pluto"},
+		{"moons", false, "Saturn Mars Earth Jupiter Venus 42 Pluto"},
+		{"name", true, "Earth Jupiter Mars Pluto Saturn Venus 42"},
+		{"name", false, "Venus Saturn Pluto Mars Jupiter Earth 42"},
+	}
+	for _, test := range tests {
+		j, err := Unmarshal([]byte(doc))
+		if err != nil {
+			t.Fatal("TestSortBy failed", err)
+		}
+		if err := j.SortBy("", test.field, test.ascending); err != nil {
+			t.Fatal("TestSortBy.SortBy failed", err)
+		}
+		names := []string{}
+		j.Range("", func(index int, item *JSON) error {
+			names = append(names, item.GetStringOr("name", item.String()))
+			return nil
+		})
+		if s := strings.Join(names, " "); s != test.want {
+			t.Fatal("TestSortBy.SortBy failed", test.field, test.ascending, s)
+		}
+	}
+
+	j := NewObject()
+	if err := j.SortBy("", "x", true); err != ErrTypeMissmatch {
+		t.Fatal("TestSortBy.SortBy failed", err)
+	}
+}
+
+func TestGetNilOut(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": 1, "l": [ 1, 2 ] }`))
+	if err != nil {
+		t.Fatal("TestGetNilOut failed", err)
+	}
+	if err := j.Get("a", (*int)(nil)); err != ErrInvalidOut {
+		t.Fatal("TestGetNilOut.Get failed", err)
+	}
+	if err := j.Get("a", (*JSON)(nil)); err != ErrInvalidOut {
+		t.Fatal("TestGetNilOut.Get failed", err)
+	}
+	if err := j.Get("l[*]", (*[]int)(nil)); err != ErrInvalidOut {
+		t.Fatal("TestGetNilOut.Get failed", err)
+	}
+	if err := j.Get("a", nil); err != ErrInvalidOut {
+		t.Fatal("TestGetNilOut.Get failed", err)
+	}
+}
+
+func TestGetUnsupportedOut(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": 1, "b": "x" }`))
+	if err != nil {
+		t.Fatal("TestGetUnsupportedOut failed", err)
+	}
+	var c chan int
+	if err := j.Get("a", &c); err != ErrInvalidOut {
+		t.Fatal("TestGetUnsupportedOut.Get failed", err)
+	}
+	var f func()
+	if err := j.Get("b", &f); err != ErrInvalidOut {
+		t.Fatal("TestGetUnsupportedOut.Get failed", err)
+	}
+	var z complex128
+	if err := j.Get("a", &z); err != ErrInvalidOut {
+		t.Fatal("TestGetUnsupportedOut.Get failed", err)
+	}
+}
+
+func TestPosition(t *testing.T) {
+
+	src := `{
+	"name": "Saturn",
+	"moons": [ { "name": "Titan", "radius": 2575 }, null ],
+	"a.b": true
+}`
+	j, err := UnmarshalWithPositions([]byte(src))
+	if err != nil {
+		t.Fatal("TestPosition failed", err)
+	}
+
+	tests := map[string]string{
+		"":                  src,
+		"name":              `"Saturn"`,
+		"moons":             `[ { "name": "Titan", "radius": 2575 }, null ]`,
+		"moons[0]":          `{ "name": "Titan", "radius": 2575 }`,
+		"moons[0].radius":   "2575",
+		"moons[1]":          "null",
+		`["a.b"]`:           "true",
+		`moons.[0]["name"]`: `"Titan"`,
+	}
+	for path, want := range tests {
+		start, end, err := j.Position(path)
+		if err != nil {
+			t.Fatal("TestPosition.Position failed", path, err)
+		}
+		if s := src[start:end]; s != want {
+			t.Fatal("TestPosition.Position failed", path, s)
+		}
+	}
+
+	if _, _, err := j.Position("moons[2]"); err != ErrNotFound {
+		t.Fatal("TestPosition.Position failed", err)
+	}
+	if _, _, err := j.Position("moons..x"); err != ErrInvalidPath {
+		t.Fatal("TestPosition.Position failed", err)
+	}
+	if _, _, err := NewObject().Position("name"); err != ErrNotFound {
+		t.Fatal("TestPosition.Position failed", err)
+	}
+}
+
+func TestGetFirst(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "server": { "host_name": "localhost", "port": 80 }, "ports": [] }`))
+	if err != nil {
+		t.Fatal("TestGetFirst failed", err)
+	}
+
+	var s string
+	if err := j.GetFirst(&s, "server.hostname", "server.host_name"); err != nil || s != "localhost" {
+		t.Fatal("TestGetFirst.GetFirst failed", s, err)
+	}
+	var i int
+	if err := j.GetFirst(&i, "ports[0]", "server.port", "port"); err != nil || i != 80 {
+		t.Fatal("TestGetFirst.GetFirst failed", i, err)
+	}
+	if err := j.GetFirst(&i, "port", "server.ports"); err != ErrNotFound {
+		t.Fatal("TestGetFirst.GetFirst failed", err)
+	}
+	if err := j.GetFirst(&i, "server.host_name", "server.port"); err != ErrInvalidOut {
+		t.Fatal("TestGetFirst.GetFirst failed", err)
+	}
+	if err := j.GetFirst(&i); err != ErrNotFound {
+		t.Fatal("TestGetFirst.GetFirst failed", err)
+	}
+}
+
+func TestGetTruncate(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "a": 3.9, "b": -3.9, "c": 300.5, "d": 0.1, "e": "7.5" }`))
+	if err != nil {
+		t.Fatal("TestGetTruncate failed", err)
+	}
+
+	var i int
+	if err := j.Get("a", &i); err != ErrTruncate {
+		t.Fatal("TestGetTruncate.Get failed", err)
+	}
+	if err := j.GetTruncate("a", &i); err != nil || i != 3 {
+		t.Fatal("TestGetTruncate.GetTruncate failed", i, err)
+	}
+	if err := j.GetTruncate("b", &i); err != nil || i != -3 {
+		t.Fatal("TestGetTruncate.GetTruncate failed", i, err)
+	}
+	var u uint8
+	if err := j.GetTruncate("c", &u); err != ErrTruncate {
+		t.Fatal("TestGetTruncate.GetTruncate failed", u, err)
+	}
+	var f float32
+	if err := j.Get("d", &f); err != ErrTruncate {
+		t.Fatal("TestGetTruncate.Get failed", err)
+	}
+	if err := j.GetTruncate("d", &f); err != nil || f != 0.1 {
+		t.Fatal("TestGetTruncate.GetTruncate failed", f, err)
+	}
+	if err := j.GetTruncate("e", &i); err != ErrInvalidOut {
+		t.Fatal("TestGetTruncate.GetTruncate failed", err)
+	}
+}
+
+func TestLeaves(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "b": [ 1, { "y": null, "x": "s" }, [] ], "a": true, "c": {} }`))
+	if err != nil {
+		t.Fatal("TestLeaves failed", err)
+	}
+	leaves := j.Leaves()
+	if s := fmt.Sprint(leaves); s != "[true 1 s <nil>]" || len(leaves) != j.CountLeaves() {
+		t.Fatal("TestLeaves.Leaves failed", s)
+	}
+	if s := fmt.Sprint(NewObject().Leaves()); s != "[]" {
+		t.Fatal("TestLeaves.Leaves failed", s)
+	}
+}
+
+func TestRootKind(t *testing.T) {
+
+	tests := map[string]string{
+		`{ "a": 1 }`: "object",
+		`[ 1, 2 ]`:   "array",
+		`"s"`:        "string",
+		`42`:         "number",
+		`false`:      "bool",
+		`null`:       "null",
+	}
+	for doc, want := range tests {
+		j, err := Unmarshal([]byte(doc))
+		if err != nil {
+			t.Fatal("TestRootKind failed", err)
+		}
+		if kind := j.RootKind(); kind != want {
+			t.Fatal("TestRootKind.RootKind failed", doc, kind)
+		}
+	}
+	if kind := (&JSON{}).RootKind(); kind != "null" {
+		t.Fatal("TestRootKind.RootKind failed", kind)
+	}
+}
+
+func TestGetJSONField(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "doc": { "name": "x", "meta": { "tags": [ "a", "b" ], "n": 2 }, "extra": [ 1 ] } }`))
+	if err != nil {
+		t.Fatal("TestGetJSONField failed", err)
+	}
+
+	var doc struct {
+		Name  string `json:"name"`
+		Meta  JSON   `json:"meta"`
+		Extra *JSON  `json:"extra"`
+	}
+	if err := j.Get("doc", &doc); err != nil {
+		t.Fatal("TestGetJSONField.Get failed", err)
+	}
+	var tag string
+	if err := doc.Meta.Get("tags[1]", &tag); err != nil || tag != "b" {
+		t.Fatal("TestGetJSONField.Get failed", tag, err)
+	}
+	if doc.Extra == nil || doc.Extra.String() != "[1]" {
+		t.Fatal("TestGetJSONField.Get failed", doc.Extra)
+	}
+
+	if err := doc.Meta.Set("n", 3); err != nil {
+		t.Fatal("TestGetJSONField.Set failed", err)
+	}
+	var n int
+	if err := j.Get("doc.meta.n", &n); err != nil || n != 2 {
+		t.Fatal("TestGetJSONField.Get failed", n, err)
+	}
+}
+
+func TestMergeDefaults(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "host": "example.com", "tls": { "enabled": true }, "tags": [ "a" ], "proxy": null }`))
+	if err != nil {
+		t.Fatal("TestMergeDefaults failed", err)
+	}
+	defaults, err := Unmarshal([]byte(`{ "host": "localhost", "port": 80, "tls": { "enabled": false, "cert": "c.pem" },
+		"tags": [ "x", "y" ], "proxy": "p", "limits": { "rate": 10 } }`))
+	if err != nil {
+		t.Fatal("TestMergeDefaults failed", err)
+	}
+
+	if err := j.MergeDefaults(defaults); err != nil {
+		t.Fatal("TestMergeDefaults.MergeDefaults failed", err)
+	}
+	want := `{"host":"example.com","limits":{"rate":10},"port":80,"proxy":null,"tags":["a"],"tls":{"cert":"c.pem","enabled":true}}`
+	if s := j.String(); s != want {
+		t.Fatal("TestMergeDefaults.MergeDefaults failed", s)
+	}
+	if err := j.Set("limits.rate", 20); err != nil {
+		t.Fatal("TestMergeDefaults.Set failed", err)
+	}
+	var rate int
+	if err := defaults.Get("limits.rate", &rate); err != nil || rate != 10 {
+		t.Fatal("TestMergeDefaults.MergeDefaults failed", rate, err)
+	}
+
+	if err := j.MergeDefaults(nil); err != ErrInvalidIn {
+		t.Fatal("TestMergeDefaults.MergeDefaults failed", err)
+	}
+	if err := j.MergeDefaults(NewArray()); err != ErrTypeMissmatch {
+		t.Fatal("TestMergeDefaults.MergeDefaults failed", err)
+	}
+}
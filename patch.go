@@ -0,0 +1,357 @@
+// Copyright (c) 2018 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a GNU GPLv3 license found in the
+// acompanying "LICENSE" file.
+
+package jsonobj
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ApplyPatch applies a JSON Patch document as defined by RFC 6902 to the
+// JSON. Patch must be an Array of operation Objects; supported operations
+// are "add", "remove", "replace", "move", "copy" and "test".
+//
+// The "path" and "from" members are JSON Pointers (RFC 6901) and are
+// translated to paths of this package.
+//
+// Operations are applied in order to a copy of the JSON which replaces the
+// JSON only if all operations succeeded. If a "test" operation fails
+// returns ErrTestFailed, if the patch is malformed returns ErrInvalidPatch.
+func (j *JSON) ApplyPatch(patch []byte) error {
+
+	var ops []map[string]interface{}
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return ErrInvalidPatch
+	}
+
+	w := &JSON{intf: clone(j.intf)}
+	for _, op := range ops {
+		if err := w.applyOp(op); err != nil {
+			return err
+		}
+	}
+	j.intf = w.intf
+
+	return nil
+}
+
+// ApplyMergePatch applies a JSON Merge Patch document as defined by RFC 7386
+// to the JSON. If patch is an Object its properties are merged into the
+// Object of the JSON recursively; a property with a null value deletes the
+// property it patches while other values, including Arrays, replace the
+// values they patch. A patch that is not an Object replaces the JSON. If
+// patch is not valid JSON returns ErrInvalidPatch.
+func (j *JSON) ApplyMergePatch(patch []byte) error {
+
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return ErrInvalidPatch
+	}
+	j.intf = merge(j.intf, p, false)
+
+	return nil
+}
+
+// Diff returns a JSON Patch document as defined by RFC 6902 which
+// transforms the JSON into other when applied with ApplyPatch.
+//
+// Objects are compared recursively by keys emitting "add", "remove" and
+// "replace" operations. Arrays are compared index by index; elements past
+// the length of the shorter Array are added or removed from the end. If
+// other is nil returns ErrInvalidIn.
+func (j *JSON) Diff(other *JSON) ([]byte, error) {
+
+	if other == nil {
+		return nil, ErrInvalidIn
+	}
+	ops := []map[string]interface{}{}
+	diff("", j.intf, other.intf, &ops)
+
+	return json.Marshal(ops)
+}
+
+// diff appends operations transforming a into b under JSON Pointer ptr
+// to ops.
+func diff(ptr string, a, b interface{}, ops *[]map[string]interface{}) {
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av))
+		for key := range av {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if val, ok := bv[key]; ok {
+				diff(ptr+"/"+escapePointer(key), av[key], val, ops)
+				continue
+			}
+			*ops = append(*ops, map[string]interface{}{
+				"op":   "remove",
+				"path": ptr + "/" + escapePointer(key),
+			})
+		}
+		keys = keys[:0]
+		for key := range bv {
+			if _, ok := av[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			*ops = append(*ops, map[string]interface{}{
+				"op":    "add",
+				"path":  ptr + "/" + escapePointer(key),
+				"value": bv[key],
+			})
+		}
+		return
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		i := 0
+		for ; i < len(av) && i < len(bv); i++ {
+			diff(ptr+"/"+strconv.Itoa(i), av[i], bv[i], ops)
+		}
+		for k := len(av) - 1; k >= i; k-- {
+			*ops = append(*ops, map[string]interface{}{
+				"op":   "remove",
+				"path": ptr + "/" + strconv.Itoa(k),
+			})
+		}
+		for ; i < len(bv); i++ {
+			*ops = append(*ops, map[string]interface{}{
+				"op":    "add",
+				"path":  ptr + "/" + strconv.Itoa(i),
+				"value": bv[i],
+			})
+		}
+		return
+	}
+
+	if !equal(a, b) {
+		*ops = append(*ops, map[string]interface{}{
+			"op":    "replace",
+			"path":  ptr,
+			"value": b,
+		})
+	}
+}
+
+// applyOp applies a single JSON Patch operation to j.
+func (j *JSON) applyOp(op map[string]interface{}) error {
+
+	name, ok := op["op"].(string)
+	if !ok {
+		return ErrInvalidPatch
+	}
+	ptr, ok := op["path"].(string)
+	if !ok {
+		return ErrInvalidPatch
+	}
+	value, hasValue := op["value"]
+	from, hasFrom := op["from"].(string)
+
+	switch name {
+	case "add":
+		if !hasValue {
+			return ErrInvalidPatch
+		}
+		return j.addPointer(ptr, value)
+	case "remove":
+		if ptr == "" {
+			return ErrInvalidPath
+		}
+		path, err := j.pointerPath(ptr)
+		if err != nil {
+			return err
+		}
+		return j.remove(path)
+	case "replace":
+		if !hasValue {
+			return ErrInvalidPatch
+		}
+		path, err := j.pointerPath(ptr)
+		if err != nil {
+			return err
+		}
+		return j.put(path, value)
+	case "move":
+		if !hasFrom {
+			return ErrInvalidPatch
+		}
+		if from == ptr {
+			return nil
+		}
+		if strings.HasPrefix(ptr, from+"/") || from == "" {
+			return ErrInvalidPath
+		}
+		path, err := j.pointerPath(from)
+		if err != nil {
+			return err
+		}
+		v, err := j.get(path)
+		if err != nil {
+			return err
+		}
+		if err := j.remove(path); err != nil {
+			return err
+		}
+		return j.addPointer(ptr, v)
+	case "copy":
+		if !hasFrom {
+			return ErrInvalidPatch
+		}
+		path, err := j.pointerPath(from)
+		if err != nil {
+			return err
+		}
+		v, err := j.get(path)
+		if err != nil {
+			return err
+		}
+		return j.addPointer(ptr, clone(v))
+	case "test":
+		if !hasValue {
+			return ErrInvalidPatch
+		}
+		path, err := j.pointerPath(ptr)
+		if err != nil {
+			return err
+		}
+		v, err := j.get(path)
+		if err != nil {
+			return err
+		}
+		if !equal(v, value) {
+			return ErrTestFailed
+		}
+		return nil
+	}
+
+	return ErrInvalidPatch
+}
+
+// addPointer performs the "add" operation of v at JSON Pointer ptr.
+func (j *JSON) addPointer(ptr string, v interface{}) error {
+
+	if ptr == "" {
+		j.intf = v
+		return nil
+	}
+
+	i := strings.LastIndex(ptr, "/")
+	if i < 0 {
+		return ErrInvalidPath
+	}
+	parent, err := j.pointerPath(ptr[:i])
+	if err != nil {
+		return err
+	}
+	ifc, err := j.get(parent)
+	if err != nil {
+		return err
+	}
+	token := unescapePointer(ptr[i+1:])
+
+	switch t := ifc.(type) {
+	case map[string]interface{}:
+		path, err := childPath(parent, t, token)
+		if err != nil {
+			return err
+		}
+		return j.put(path, v)
+	case []interface{}:
+		index := len(t)
+		if token != "-" {
+			if index, err = pointerIndex(token); err != nil {
+				return err
+			}
+		}
+		return j.insert(parent, index, v)
+	}
+
+	return ErrNotFound
+}
+
+// pointerPath translates a JSON Pointer addressing an existing element of
+// j into a path addressing the same element.
+func (j *JSON) pointerPath(ptr string) (string, error) {
+
+	if ptr == "" {
+		return "", nil
+	}
+	if ptr[0] != '/' {
+		return "", ErrInvalidPath
+	}
+
+	path := ""
+	cur := j.intf
+	for _, token := range strings.Split(ptr[1:], "/") {
+		token = unescapePointer(token)
+		var err error
+		if path, err = childPath(path, cur, token); err != nil {
+			return "", err
+		}
+		if cur, err = j.get(path); err != nil {
+			return "", err
+		}
+	}
+
+	return path, nil
+}
+
+// childPath returns the path of the child of container under path addressed
+// by JSON Pointer reference token.
+func childPath(path string, container interface{}, token string) (string, error) {
+
+	switch container.(type) {
+	case map[string]interface{}:
+		key := Path{Segment{Key: token}}.String()
+		if path == "" || key[0] == '[' {
+			return path + key, nil
+		}
+		return path + "." + key, nil
+	case []interface{}:
+		i, err := pointerIndex(token)
+		if err != nil {
+			return "", err
+		}
+		return path + "[" + strconv.Itoa(i) + "]", nil
+	}
+
+	return "", ErrNotFound
+}
+
+// pointerIndex parses a JSON Pointer reference token as an Array index.
+func pointerIndex(token string) (int, error) {
+
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return -1, ErrInvalidPath
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 {
+		return -1, ErrInvalidPath
+	}
+	return i, nil
+}
+
+// unescapePointer unescapes a JSON Pointer reference token.
+func unescapePointer(token string) string {
+	return strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+}
+
+// escapePointer escapes s for use as a JSON Pointer reference token.
+func escapePointer(s string) string {
+	return strings.Replace(strings.Replace(s, "~", "~0", -1), "/", "~1", -1)
+}
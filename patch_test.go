@@ -0,0 +1,145 @@
+package jsonobj
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+
+	const json = `{
+		"name": "Saturn",
+		"moons": [ "Titan", "Rhea" ],
+		"rings": { "count": 7 }
+}`
+	const patch = `[
+		{ "op": "test", "path": "/name", "value": "Saturn" },
+		{ "op": "add", "path": "/moons/1", "value": "Iapetus" },
+		{ "op": "add", "path": "/moons/-", "value": "Dione" },
+		{ "op": "remove", "path": "/moons/0" },
+		{ "op": "replace", "path": "/rings/count", "value": 8 },
+		{ "op": "copy", "from": "/rings", "path": "/halo" },
+		{ "op": "move", "from": "/name", "path": "/title" },
+		{ "op": "add", "path": "/a~1b", "value": true }
+]`
+	const want = `{"a/b":true,"halo":{"count":8},"moons":["Iapetus","Rhea","Dione"],"rings":{"count":8},"title":"Saturn"}`
+
+	j, err := Unmarshal([]byte(json))
+	if err != nil {
+		t.Fatal("TestApplyPatch failed", err)
+	}
+	if err := j.ApplyPatch([]byte(patch)); err != nil {
+		t.Fatal("TestApplyPatch.ApplyPatch failed", err)
+	}
+	out, err := j.Export("")
+	if err != nil {
+		t.Fatal("TestApplyPatch.Export failed", err)
+	}
+	if string(out) != want {
+		t.Fatal("TestApplyPatch.ApplyPatch failed", string(out))
+	}
+
+	// Failed patch must leave the JSON unchanged.
+	const failing = `[
+		{ "op": "remove", "path": "/halo" },
+		{ "op": "test", "path": "/title", "value": "Uranus" }
+]`
+	if err := j.ApplyPatch([]byte(failing)); err != ErrTestFailed {
+		t.Fatal("TestApplyPatch.ApplyPatch failed", err)
+	}
+	if out, _ = j.Export(""); string(out) != want {
+		t.Fatal("TestApplyPatch.ApplyPatch failed", string(out))
+	}
+
+	if err := j.ApplyPatch([]byte(`[{ "op": "add", "path": "/a.b", "value": 1 }, { "op": "add", "path": "/", "value": 2 }]`)); err != nil {
+		t.Fatal("TestApplyPatch.ApplyPatch failed", err)
+	}
+	if err := j.ApplyPatch([]byte(`[{ "op": "test", "path": "/a.b", "value": 1 }, { "op": "remove", "path": "/a.b" }, { "op": "remove", "path": "/" }]`)); err != nil {
+		t.Fatal("TestApplyPatch.ApplyPatch failed", err)
+	}
+	if err := j.ApplyPatch([]byte(`[{ "op": "add", "path": "/a/b", "value": 1 }]`)); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestApplyPatch.ApplyPatch failed", err)
+	}
+	if err := j.ApplyPatch([]byte(`[{ "op": "move", "from": "/rings", "path": "/rings/inner" }]`)); err != ErrInvalidPath {
+		t.Fatal("TestApplyPatch.ApplyPatch failed", err)
+	}
+	if err := j.ApplyPatch([]byte(`[{ "op": "jump", "path": "/title" }]`)); err != ErrInvalidPatch {
+		t.Fatal("TestApplyPatch.ApplyPatch failed", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+
+	docs := [][2]string{
+		{`{"a":1,"b":[1,2,3],"c":{"d":"e"},"x/y":0}`, `{"a":2,"b":[1,5],"c":{"f":null},"g":[true]}`},
+		{`[1,{"a":1},3]`, `[1,{"a":[1]},3,4,5]`},
+		{`{"a":1}`, `[1]`},
+		{`{"a":1}`, `{"a":1}`},
+	}
+
+	for _, doc := range docs {
+		a, err := Unmarshal([]byte(doc[0]))
+		if err != nil {
+			t.Fatal("TestDiff failed", err)
+		}
+		b, err := Unmarshal([]byte(doc[1]))
+		if err != nil {
+			t.Fatal("TestDiff failed", err)
+		}
+		patch, err := a.Diff(b)
+		if err != nil {
+			t.Fatal("TestDiff.Diff failed", err)
+		}
+		if err := a.ApplyPatch(patch); err != nil {
+			t.Fatal("TestDiff.ApplyPatch failed", err, string(patch))
+		}
+		if !a.Equal(b) {
+			t.Fatal("TestDiff.Diff failed", string(patch))
+		}
+	}
+
+	if _, err := NewObject().Diff(nil); err != ErrInvalidIn {
+		t.Fatal("TestDiff.Diff failed", err)
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+
+	// Test cases from RFC 7386 Appendix A.
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, test := range tests {
+		j, err := Unmarshal([]byte(test.target))
+		if err != nil {
+			t.Fatal("TestApplyMergePatch failed", err)
+		}
+		if err := j.ApplyMergePatch([]byte(test.patch)); err != nil {
+			t.Fatal("TestApplyMergePatch.ApplyMergePatch failed", test.patch, err)
+		}
+		if s := j.String(); s != test.want {
+			t.Fatal("TestApplyMergePatch.ApplyMergePatch failed", test.target, test.patch, s)
+		}
+	}
+
+	j := NewObject()
+	if err := j.ApplyMergePatch([]byte(`{`)); err != ErrInvalidPatch {
+		t.Fatal("TestApplyMergePatch.ApplyMergePatch failed", err)
+	}
+}
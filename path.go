@@ -0,0 +1,217 @@
+// Copyright (c) 2018 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a GNU GPLv3 license found in the
+// acompanying "LICENSE" file.
+
+package jsonobj
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Segment is a single step of a Path addressing either an Object property by
+// Key or an Array element by Index.
+type Segment struct {
+	Key      string // Key is the Object property name.
+	Index    int    // Index is the Array element index.
+	Array    bool   // Array is true if Segment addresses an Array element.
+	Wildcard bool   // Wildcard is true if Segment addresses all Array elements.
+	Append   bool   // Append is true if Segment addresses a new Array element.
+}
+
+// Path is a parsed path as accepted by Get and Set. An empty Path addresses
+// the root element.
+type Path []Segment
+
+// ParsePath parses path into a Path which can be used with GetPath and
+// SetPath to avoid parsing the same path on each access. If path is
+// malformed returns ErrInvalidPath.
+func ParsePath(path string) (Path, error) {
+
+	p, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+	return append(Path{}, p...), nil
+}
+
+// HasWildcard returns true if p contains a wildcard Segment.
+func (p Path) HasWildcard() bool {
+
+	for _, seg := range p {
+		if seg.Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the path string p was parsed from in its canonical form.
+// Object keys which are empty or contain any of the characters `.[]"` are
+// written quoted in square brackets.
+func (p Path) String() string {
+
+	sb := strings.Builder{}
+	for i, seg := range p {
+		if seg.Wildcard {
+			sb.WriteString("[*]")
+			continue
+		}
+		if seg.Append {
+			sb.WriteString("[]")
+			continue
+		}
+		if seg.Array {
+			sb.WriteString("[" + strconv.Itoa(seg.Index) + "]")
+			continue
+		}
+		if seg.Key == "" || strings.ContainsAny(seg.Key, `.[]"`) {
+			sb.WriteString("[" + strconv.Quote(seg.Key) + "]")
+			continue
+		}
+		if i > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(seg.Key)
+	}
+	return sb.String()
+}
+
+// PathError is returned when a path could not be resolved. It records the
+// segment at which resolution failed and wraps the base error describing the
+// failure, i.e. ErrNotFound or ErrOutOfRange, so it can be tested for with
+// errors.Is.
+type PathError struct {
+	Path    string  // Path is the path being resolved.
+	Element string  // Element is Path up to and including the failed Segment.
+	Segment Segment // Segment is the segment at which resolution failed.
+	Err     error   // Err is the base error.
+}
+
+// Error implements the Error interface.
+func (err *PathError) Error() string {
+	return err.Err.Error() + ": " + strconv.Quote(err.Element) + " in path " + strconv.Quote(err.Path)
+}
+
+// Unwrap returns the base error.
+func (err *PathError) Unwrap() error {
+	return err.Err
+}
+
+// newPathError returns a PathError wrapping err for failure resolving
+// segment i of path.
+func newPathError(path Path, i int, err error) error {
+	return &PathError{
+		Path:    path.String(),
+		Element: path[:i+1].String(),
+		Segment: path[i],
+		Err:     err,
+	}
+}
+
+// maxCachedPaths is the maximum number of paths kept in pathCache.
+const maxCachedPaths = 4096
+
+var (
+	// pathCache caches parsed paths keyed by their path string.
+	pathCache sync.Map
+
+	// cachedPaths is the number of paths in pathCache.
+	cachedPaths int64
+)
+
+// parse returns the Path for path string from pathCache, parsing and
+// caching it if it was not cached. Returned Path must not be modified.
+// Once the cache is full paths are parsed without being cached.
+func parse(path string) (Path, error) {
+
+	if p, ok := pathCache.Load(path); ok {
+		return p.(Path), nil
+	}
+	p, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if atomic.LoadInt64(&cachedPaths) < maxCachedPaths {
+		if _, loaded := pathCache.LoadOrStore(path, p); !loaded {
+			atomic.AddInt64(&cachedPaths, 1)
+		}
+	}
+	return p, nil
+}
+
+// parsePath parses path into a Path. Path elements are separated by dots and
+// each element is an optional Object key followed by any number of Array
+// indexes, "*" wildcards, nothing, denoting an append, or double quoted
+// Object keys in square brackets. Quoted keys are unquoted as Go string
+// literals and may contain any character.
+func parsePath(path string) (Path, error) {
+
+	result := Path{}
+	if path == "" {
+		return result, nil
+	}
+
+	for i := 0; ; i++ {
+		n := len(result)
+		a := i
+		for i < len(path) && path[i] != '.' && path[i] != '[' && path[i] != ']' {
+			i++
+		}
+		if i > a {
+			result = append(result, Segment{Key: path[a:i]})
+		}
+		for i < len(path) && path[i] == '[' {
+			if i+1 < len(path) && path[i+1] == '"' {
+				b := i + 2
+				for b < len(path) && path[b] != '"' {
+					if path[b] == '\\' {
+						b++
+					}
+					b++
+				}
+				if b+1 >= len(path) || path[b+1] != ']' {
+					return nil, ErrInvalidPath
+				}
+				key, err := strconv.Unquote(path[i+1 : b+1])
+				if err != nil {
+					return nil, ErrInvalidPath
+				}
+				result = append(result, Segment{Key: key})
+				i = b + 2
+				continue
+			}
+			b := strings.IndexByte(path[i:], ']')
+			if b < 0 {
+				return nil, ErrInvalidPath
+			}
+			b += i
+			switch index := path[i+1 : b]; index {
+			case "":
+				result = append(result, Segment{Append: true})
+			case "*":
+				result = append(result, Segment{Wildcard: true})
+			default:
+				n, err := strconv.Atoi(index)
+				if err != nil || n < 0 {
+					return nil, ErrInvalidPath
+				}
+				result = append(result, Segment{Index: n, Array: true})
+			}
+			i = b + 1
+		}
+		if len(result) == n {
+			return nil, ErrInvalidPath
+		}
+		if i == len(path) {
+			break
+		}
+		if path[i] != '.' {
+			return nil, ErrInvalidPath
+		}
+	}
+
+	return result, nil
+}
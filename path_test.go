@@ -0,0 +1,292 @@
+package jsonobj
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+
+	valid := map[string]string{
+		"":                  "",
+		"a":                 "a",
+		"a.b":               "a.b",
+		"planets[0].name":   "planets[0].name",
+		"[42]":              "[42]",
+		"a.[1]":             "a[1]",
+		"matrix[1][2]":      "matrix[1][2]",
+		"[0][1].x":          "[0][1].x",
+		"a.b[10].c[0][0].d": "a.b[10].c[0][0].d",
+		"a[*].b[*][0]":      "a[*].b[*][0]",
+		"a.tags[]":          "a.tags[]",
+		"[][0][].x":         "[][0][].x",
+		`["a.b"]`:           `["a.b"]`,
+		`a["b"].c`:          "a.b.c",
+		`a.["b"]["c"][0]`:   "a.b.c[0]",
+		`["x[0]"]["q\"t"]`:  `["x[0]"]["q\"t"]`,
+		`[""]`:              `[""]`,
+		`["]"].b`:           `["]"].b`,
+	}
+	for path, want := range valid {
+		p, err := ParsePath(path)
+		if err != nil {
+			t.Fatal("TestParsePath.ParsePath failed", path, err)
+		}
+		if p.String() != want {
+			t.Fatal("TestParsePath.String failed", path, p.String())
+		}
+	}
+
+	invalid := []string{".", "a.", ".a", "a..b", "a[", "a]", "a[x]", "a[-1]", "a[0]b", "a[] ", "[0]]",
+		`["a`, `["a"`, `["a"b]`, `["a"]b`, `["\q"]`, `a"b"]`}
+	for _, path := range invalid {
+		if _, err := ParsePath(path); err != ErrInvalidPath {
+			t.Fatal("TestParsePath.ParsePath failed", path, err)
+		}
+	}
+}
+
+func TestGetSetPath(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "planets": [ { "name": "Saturn", "moons": 62 } ] }`))
+	if err != nil {
+		t.Fatal("TestGetSetPath failed", err)
+	}
+	p, err := ParsePath("planets[0].moons")
+	if err != nil {
+		t.Fatal("TestGetSetPath.ParsePath failed", err)
+	}
+	if err := j.SetPath(p, 82); err != nil {
+		t.Fatal("TestGetSetPath.SetPath failed", err)
+	}
+	var moons int
+	if err := j.GetPath(p, &moons); err != nil || moons != 82 {
+		t.Fatal("TestGetSetPath.GetPath failed", moons, err)
+	}
+}
+
+// benchJSON is the document used by benchmarks.
+const benchJSON = `{ "planets": [ { "name": "Saturn", "moons": [ { "name": "Titan", "radius": 2575 } ] } ] }`
+
+func BenchmarkGet(b *testing.B) {
+
+	j, err := Unmarshal([]byte(benchJSON))
+	if err != nil {
+		b.Fatal(err)
+	}
+	var radius int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := j.Get("planets[0].moons[0].radius", &radius); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetPath(b *testing.B) {
+
+	j, err := Unmarshal([]byte(benchJSON))
+	if err != nil {
+		b.Fatal(err)
+	}
+	p, err := ParsePath("planets[0].moons[0].radius")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var radius int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := j.GetPath(p, &radius); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetUncached measures Get parsing the path on each call as it did
+// before parsed paths were cached.
+func BenchmarkGetUncached(b *testing.B) {
+
+	j, err := Unmarshal([]byte(benchJSON))
+	if err != nil {
+		b.Fatal(err)
+	}
+	var radius int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, err := parsePath("planets[0].moons[0].radius")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := j.GetPath(p, &radius); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPathError(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "planets": [ { "name": "Saturn", "moons": 62 } ] }`))
+	if err != nil {
+		t.Fatal("TestPathError failed", err)
+	}
+
+	var name string
+	err = j.Get("planets[0].rings.count", &name)
+	perr, ok := err.(*PathError)
+	if !ok {
+		t.Fatal("TestPathError.Get failed", err)
+	}
+	if perr.Path != "planets[0].rings.count" || perr.Element != "planets[0].rings" ||
+		perr.Segment.Key != "rings" || perr.Err != ErrNotFound {
+		t.Fatal("TestPathError.Get failed", perr)
+	}
+	if perr.Error() != `element not found: "planets[0].rings" in path "planets[0].rings.count"` {
+		t.Fatal("TestPathError.Error failed", perr.Error())
+	}
+
+	err = j.Get("planets[3].name", &name)
+	if perr, ok = err.(*PathError); !ok || perr.Element != "planets[3]" ||
+		!perr.Segment.Array || perr.Segment.Index != 3 || perr.Err != ErrOutOfRange {
+		t.Fatal("TestPathError.Get failed", err)
+	}
+}
+
+func TestErrorsIs(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "planets": [ { "name": "Saturn" } ] }`))
+	if err != nil {
+		t.Fatal("TestErrorsIs failed", err)
+	}
+
+	var name string
+	err = j.Get("planets[1].name", &name)
+	if !errors.Is(err, ErrOutOfRange) || errors.Is(err, ErrNotFound) {
+		t.Fatal("TestErrorsIs.Is failed", err)
+	}
+
+	wrapped := fmt.Errorf("reading config: %w", j.Get("planets[0].moons", &name))
+	if !errors.Is(wrapped, ErrNotFound) {
+		t.Fatal("TestErrorsIs.Is failed", wrapped)
+	}
+	var perr *PathError
+	if !errors.As(wrapped, &perr) || perr.Element != "planets[0].moons" {
+		t.Fatal("TestErrorsIs.As failed", wrapped)
+	}
+	var jerr *ErrJSON
+	if !errors.As(wrapped, &jerr) || jerr != ErrNotFound {
+		t.Fatal("TestErrorsIs.As failed", wrapped)
+	}
+	if errors.Unwrap(perr) != ErrNotFound {
+		t.Fatal("TestErrorsIs.Unwrap failed", perr)
+	}
+
+	if err := j.Get("planets..name", &name); !errors.Is(err, ErrInvalidPath) {
+		t.Fatal("TestErrorsIs.Is failed", err)
+	}
+}
+
+func TestSetAppend(t *testing.T) {
+
+	j := NewObject()
+	if err := j.Set("a.b.tags[]", "first"); err != nil {
+		t.Fatal("TestSetAppend.Set failed", err)
+	}
+	if err := j.Set("a.b.tags[]", "second"); err != nil {
+		t.Fatal("TestSetAppend.Set failed", err)
+	}
+	if err := j.Set("items[].name", "x"); err != nil {
+		t.Fatal("TestSetAppend.Set failed", err)
+	}
+	if err := j.Set("items[].name", "y"); err != nil {
+		t.Fatal("TestSetAppend.Set failed", err)
+	}
+	if s := j.String(); s != `{"a":{"b":{"tags":["first","second"]}},"items":[{"name":"x"},{"name":"y"}]}` {
+		t.Fatal("TestSetAppend.Set failed", s)
+	}
+
+	if err := j.Set("a.b[]", 1); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestSetAppend.Set failed", err)
+	}
+	var s string
+	if err := j.Get("a.b.tags[]", &s); err != ErrInvalidPath {
+		t.Fatal("TestSetAppend.Get failed", err)
+	}
+}
+
+func TestRootArray(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`[ { "field": "x", "list": [ 1, 2 ] }, [ 10, [ 20, 21 ] ] ]`))
+	if err != nil {
+		t.Fatal("TestRootArray failed", err)
+	}
+
+	var s string
+	if err := j.Get("[0].field", &s); err != nil || s != "x" {
+		t.Fatal("TestRootArray.Get failed", s, err)
+	}
+	var i int
+	if err := j.Get("[0].list[1]", &i); err != nil || i != 2 {
+		t.Fatal("TestRootArray.Get failed", i, err)
+	}
+	if err := j.Get("[1][0]", &i); err != nil || i != 10 {
+		t.Fatal("TestRootArray.Get failed", i, err)
+	}
+	if err := j.Get("[1][1][1]", &i); err != nil || i != 21 {
+		t.Fatal("TestRootArray.Get failed", i, err)
+	}
+	m := map[string]interface{}{}
+	if err := j.Get("[0]", &m); err != nil || m["field"] != "x" {
+		t.Fatal("TestRootArray.Get failed", m, err)
+	}
+
+	if err := j.Set("[0].field", "y"); err != nil {
+		t.Fatal("TestRootArray.Set failed", err)
+	}
+	if err := j.Set("[1][1][0]", 0); err != nil {
+		t.Fatal("TestRootArray.Set failed", err)
+	}
+	if s := j.String(); s != `[{"field":"y","list":[1,2]},[10,[0,21]]]` {
+		t.Fatal("TestRootArray.Set failed", s)
+	}
+
+	if err := j.Get("[2]", &i); !errors.Is(err, ErrOutOfRange) {
+		t.Fatal("TestRootArray.Get failed", err)
+	}
+	if err := j.Get("[0][0]", &i); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestRootArray.Get failed", err)
+	}
+	if err := j.Get("field", &s); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestRootArray.Get failed", err)
+	}
+}
+
+func TestQuotedKeys(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "weird.key[with]brackets": { "say \"hi\"": [ 1, 2 ] }, "": 3 }`))
+	if err != nil {
+		t.Fatal("TestQuotedKeys failed", err)
+	}
+
+	var i int
+	if err := j.Get(`["weird.key[with]brackets"]["say \"hi\""][1]`, &i); err != nil || i != 2 {
+		t.Fatal("TestQuotedKeys.Get failed", i, err)
+	}
+	if err := j.Get(`[""]`, &i); err != nil || i != 3 {
+		t.Fatal("TestQuotedKeys.Get failed", i, err)
+	}
+	if err := j.Set(`["a.b"].c`, 4); err != nil {
+		t.Fatal("TestQuotedKeys.Set failed", err)
+	}
+	if err := j.Get(`["a.b"].c`, &i); err != nil || i != 4 {
+		t.Fatal("TestQuotedKeys.Get failed", i, err)
+	}
+	if err := j.Get("a.b.c", &i); !errors.Is(err, ErrNotFound) {
+		t.Fatal("TestQuotedKeys.Get failed", err)
+	}
+
+	paths, err := j.FindKeys("c")
+	if err != nil || len(paths) != 1 || paths[0] != `["a.b"].c` {
+		t.Fatal("TestQuotedKeys.FindKeys failed", paths, err)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2018 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a GNU GPLv3 license found in the
+// acompanying "LICENSE" file.
+
+package jsonobj
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Query returns values of all elements of the JSON matched by a JSONPath
+// expression. A subset of JSONPath is supported: the expression starts with
+// "$" denoting the root element followed by any number of:
+//
+//	.name or ['name']  the property name of an Object
+//	[n]                the element at index n of an Array, negative n
+//	                   counting from the end
+//	[start:end]        elements of an Array from index start up to but not
+//	                   including end; either may be omitted or negative
+//	.* or [*]          all properties of an Object or elements of an Array
+//
+// Property names in brackets may be single or double quoted. Object
+// properties matched by a wildcard are visited in key order. Elements which
+// do not match, i.e. missing properties or indexes out of range, are
+// skipped, so Query returns an empty slice if nothing matched. Objects and
+// Arrays in the result are shared with the JSON, not copied. If jsonpath is
+// malformed or uses unsupported operators, such as ".." recursive descent
+// or filters, returns ErrInvalidPath.
+func (j *JSON) Query(jsonpath string) ([]interface{}, error) {
+
+	if jsonpath == "" || jsonpath[0] != '$' {
+		return nil, ErrInvalidPath
+	}
+
+	nodes := []interface{}{j.intf}
+	for i := 1; i < len(jsonpath); {
+		switch jsonpath[i] {
+		case '.':
+			i++
+			if i < len(jsonpath) && jsonpath[i] == '*' {
+				nodes = queryChildren(nodes)
+				i++
+				continue
+			}
+			a := i
+			for i < len(jsonpath) && jsonpath[i] != '.' && jsonpath[i] != '[' {
+				i++
+			}
+			if i == a {
+				return nil, ErrInvalidPath
+			}
+			nodes = queryKey(nodes, jsonpath[a:i])
+		case '[':
+			b, err := queryBracket(jsonpath, i)
+			if err != nil {
+				return nil, err
+			}
+			if nodes, err = querySelect(nodes, jsonpath[i+1:b]); err != nil {
+				return nil, err
+			}
+			i = b + 1
+		default:
+			return nil, ErrInvalidPath
+		}
+	}
+
+	return nodes, nil
+}
+
+// queryBracket returns the index of the square bracket closing the bracket
+// opened at index i of jsonpath, skipping over quoted property names.
+func queryBracket(jsonpath string, i int) (int, error) {
+
+	if i+1 < len(jsonpath) && (jsonpath[i+1] == '\'' || jsonpath[i+1] == '"') {
+		quote := jsonpath[i+1]
+		b := i + 2
+		for b < len(jsonpath) && jsonpath[b] != quote {
+			if jsonpath[b] == '\\' {
+				b++
+			}
+			b++
+		}
+		if b+1 >= len(jsonpath) || jsonpath[b+1] != ']' {
+			return -1, ErrInvalidPath
+		}
+		return b + 1, nil
+	}
+	b := strings.IndexByte(jsonpath[i:], ']')
+	if b < 0 {
+		return -1, ErrInvalidPath
+	}
+	return i + b, nil
+}
+
+// querySelect applies the bracketed selector sel to nodes.
+func querySelect(nodes []interface{}, sel string) ([]interface{}, error) {
+
+	if sel == "*" {
+		return queryChildren(nodes), nil
+	}
+	if sel != "" && (sel[0] == '\'' || sel[0] == '"') {
+		key, err := queryUnquote(sel)
+		if err != nil {
+			return nil, err
+		}
+		return queryKey(nodes, key), nil
+	}
+	if c := strings.IndexByte(sel, ':'); c >= 0 {
+		start, err := queryInt(sel[:c])
+		if err != nil {
+			return nil, err
+		}
+		end, err := queryInt(sel[c+1:])
+		if err != nil {
+			return nil, err
+		}
+		return querySlice(nodes, start, end), nil
+	}
+	n, err := strconv.Atoi(sel)
+	if err != nil {
+		return nil, ErrInvalidPath
+	}
+	result := []interface{}{}
+	for _, node := range nodes {
+		if slc, ok := node.([]interface{}); ok {
+			i := n
+			if i < 0 {
+				i += len(slc)
+			}
+			if i >= 0 && i < len(slc) {
+				result = append(result, slc[i])
+			}
+		}
+	}
+	return result, nil
+}
+
+// queryUnquote unquotes a single or double quoted property name where a
+// backslash escapes the character following it.
+func queryUnquote(s string) (string, error) {
+
+	if s[0] == '"' {
+		key, err := strconv.Unquote(s)
+		if err != nil {
+			return "", ErrInvalidPath
+		}
+		return key, nil
+	}
+	sb := strings.Builder{}
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String(), nil
+}
+
+// queryInt parses an optional slice bound, returning nil if s is empty.
+func queryInt(s string) (*int, error) {
+
+	if s == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, ErrInvalidPath
+	}
+	return &n, nil
+}
+
+// queryKey returns values of property key of Objects in nodes.
+func queryKey(nodes []interface{}, key string) []interface{} {
+
+	result := []interface{}{}
+	for _, node := range nodes {
+		if m, ok := node.(map[string]interface{}); ok {
+			if v, ok := m[key]; ok {
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
+
+// queryChildren returns property values of Objects in key order and elements
+// of Arrays in nodes.
+func queryChildren(nodes []interface{}) []interface{} {
+
+	result := []interface{}{}
+	for _, node := range nodes {
+		switch t := node.(type) {
+		case map[string]interface{}:
+			for _, key := range sortedKeys(t) {
+				result = append(result, t[key])
+			}
+		case []interface{}:
+			result = append(result, t...)
+		}
+	}
+	return result
+}
+
+// querySlice returns elements of Arrays in nodes from index start up to but
+// not including end. Nil bounds default to the bounds of each Array and
+// negative bounds count from its end.
+func querySlice(nodes []interface{}, start, end *int) []interface{} {
+
+	// bound resolves bound b for an Array of length n.
+	bound := func(b *int, n, def int) int {
+		if b == nil {
+			return def
+		}
+		i := *b
+		if i < 0 {
+			i += n
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > n {
+			return n
+		}
+		return i
+	}
+
+	result := []interface{}{}
+	for _, node := range nodes {
+		if slc, ok := node.([]interface{}); ok {
+			a, b := bound(start, len(slc), 0), bound(end, len(slc), len(slc))
+			if a < b {
+				result = append(result, slc[a:b]...)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package jsonobj
+
+import (
+	"fmt"
+	"testing"
+)
+
+// storeJSON is the example document of the JSONPath specification.
+const storeJSON = `{ "store": {
+	"book": [
+		{ "category": "reference",
+		  "author": "Nigel Rees",
+		  "title": "Sayings of the Century",
+		  "price": 8.95
+		},
+		{ "category": "fiction",
+		  "author": "Evelyn Waugh",
+		  "title": "Sword of Honour",
+		  "price": 12.99
+		},
+		{ "category": "fiction",
+		  "author": "Herman Melville",
+		  "title": "Moby Dick",
+		  "isbn": "0-553-21311-3",
+		  "price": 8.99
+		},
+		{ "category": "fiction",
+		  "author": "J. R. R. Tolkien",
+		  "title": "The Lord of the Rings",
+		  "isbn": "0-395-19395-8",
+		  "price": 22.99
+		}
+	],
+	"bicycle": {
+		"color": "red",
+		"price": 19.95
+	}
+} }`
+
+func TestQuery(t *testing.T) {
+
+	j, err := Unmarshal([]byte(storeJSON))
+	if err != nil {
+		t.Fatal("TestQuery failed", err)
+	}
+
+	tests := map[string]string{
+		"$.store.book[*].author":      "[Nigel Rees Evelyn Waugh Herman Melville J. R. R. Tolkien]",
+		"$.store.*.price":             "[19.95]",
+		"$.store.bicycle.*":           "[red 19.95]",
+		"$.store.book[2].title":       "[Moby Dick]",
+		"$.store.book[-1].title":      "[The Lord of the Rings]",
+		"$.store.book[0:2].title":     "[Sayings of the Century Sword of Honour]",
+		"$.store.book[:2].price":      "[8.95 12.99]",
+		"$.store.book[-2:].isbn":      "[0-553-21311-3 0-395-19395-8]",
+		"$.store.book[1:10].category": "[fiction fiction fiction]",
+		"$.store.book[*].isbn":        "[0-553-21311-3 0-395-19395-8]",
+		"$['store']['bicycle'].color": "[red]",
+		`$["store"].book[3]["title"]`: "[The Lord of the Rings]",
+		"$.store.book[4].title":       "[]",
+		"$.store.book[2:1].title":     "[]",
+		"$.store.toys[*]":             "[]",
+		"$.store.book.title":          "[]",
+	}
+	for path, want := range tests {
+		v, err := j.Query(path)
+		if err != nil {
+			t.Fatal("TestQuery.Query failed", path, err)
+		}
+		if s := fmt.Sprint(v); s != want {
+			t.Fatal("TestQuery.Query failed", path, s)
+		}
+	}
+
+	if j, err = Unmarshal([]byte(`{ "a": [ [ 1, 2, 3 ], [ 4, 5, 6, 7, 8 ], [] ] }`)); err != nil {
+		t.Fatal("TestQuery failed", err)
+	}
+	if v, err := j.Query("$.a[*][-1]"); err != nil || fmt.Sprint(v) != "[3 8]" {
+		t.Fatal("TestQuery.Query failed", v, err)
+	}
+	if j, err = Unmarshal([]byte(storeJSON)); err != nil {
+		t.Fatal("TestQuery failed", err)
+	}
+
+	v, err := j.Query("$")
+	if err != nil || len(v) != 1 {
+		t.Fatal("TestQuery.Query failed", v, err)
+	}
+	if v, err = j.Query("$.store.*"); err != nil || len(v) != 2 {
+		t.Fatal("TestQuery.Query failed", v, err)
+	}
+
+	invalid := []string{"", "store", "$..author", "$.", "$.store.", "$[", "$[x]", "$['a]", "$['a'x]",
+		"$[1:x]", "$.store.book[?(@.price < 10)]", "$store"}
+	for _, path := range invalid {
+		if _, err := j.Query(path); err != ErrInvalidPath {
+			t.Fatal("TestQuery.Query failed", path, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2018 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a GNU GPLv3 license found in the
+// acompanying "LICENSE" file.
+
+package jsonobj
+
+import "sync"
+
+// SyncJSON wraps a JSON for safe concurrent use by multiple goroutines.
+// Get and Len take a read lock while Set and Delete take a write lock.
+//
+// JSON itself does no locking and is meant for use by a single goroutine.
+// The wrapped JSON must not be accessed directly while in use by SyncJSON.
+// Note that Objects and Arrays assigned to interface outputs by Get are
+// shared with the JSON and are not guarded by the lock.
+type SyncJSON struct {
+	mu sync.RWMutex // mu guards j.
+	j  *JSON        // j is the wrapped JSON.
+}
+
+// NewSyncJSON returns a new SyncJSON wrapping j.
+func NewSyncJSON(j *JSON) *SyncJSON {
+	return &SyncJSON{j: j}
+}
+
+// Get is like JSON.Get.
+func (s *SyncJSON) Get(path string, out interface{}) error {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.j.Get(path, out)
+}
+
+// Set is like JSON.Set.
+func (s *SyncJSON) Set(path string, in interface{}) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.j.Set(path, in)
+}
+
+// Delete is like JSON.Delete.
+func (s *SyncJSON) Delete(path string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.j.Delete(path)
+}
+
+// Len is like JSON.Len.
+func (s *SyncJSON) Len(path string) (int, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.j.Len(path)
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2018 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a GNU GPLv3 license found in the
+// acompanying "LICENSE" file.
+
+package jsonobj
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// TestSyncJSON is meant to be run with the race detector enabled.
+func TestSyncJSON(t *testing.T) {
+
+	j, err := Unmarshal([]byte(`{ "counters": { "0": 0 }, "log": [] }`))
+	if err != nil {
+		t.Fatal("TestSyncJSON failed", err)
+	}
+	if err := j.Set("log", make([]int, 100)); err != nil {
+		t.Fatal("TestSyncJSON failed", err)
+	}
+	s := NewSyncJSON(j)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				if err := s.Set("counters."+strconv.Itoa(i), k); err != nil {
+					t.Error("TestSyncJSON.Set failed", err)
+					return
+				}
+				if err := s.Set("log["+strconv.Itoa(k)+"]", i); err != nil {
+					t.Error("TestSyncJSON.Set failed", err)
+					return
+				}
+			}
+			if err := s.Delete("counters." + strconv.Itoa(i)); err != nil {
+				t.Error("TestSyncJSON.Delete failed", err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				var v int
+				if err := s.Get("log[0]", &v); err != nil {
+					t.Error("TestSyncJSON.Get failed", err)
+					return
+				}
+				if _, err := s.Len("log"); err != nil {
+					t.Error("TestSyncJSON.Len failed", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n, err := s.Len("log"); err != nil || n != 100 {
+		t.Fatal("TestSyncJSON.Len failed", n, err)
+	}
+}